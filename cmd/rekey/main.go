@@ -0,0 +1,49 @@
+// Command rekey re-encrypts stored credentials under ENCRYPTION_ACTIVE_KEY.
+//
+// Rotation runs without downtime: first deploy the new key to every server
+// alongside the old one, switch ENCRYPTION_ACTIVE_KEY to the new version, then
+// run this command. Old keys can be removed once a run re-encrypts nothing.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"vigil/internal/config"
+	"vigil/internal/database"
+	"vigil/internal/encryption"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", 500, "number of rows to read per query")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	keyring, err := encryption.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Fatalf("ENCRYPTION_KEYS must be set to re-encrypt credentials")
+	}
+
+	// Initialize database
+	db, err := database.New(cfg.DatabaseURL, keyring)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	log.Printf("Re-encrypting credentials under key version %s", keyring.ActiveVersion())
+
+	updated, err := db.Reencrypt(keyring, *batchSize)
+	if err != nil {
+		log.Fatalf("Re-encryption stopped after %d values: %v", updated, err)
+	}
+
+	log.Printf("Re-encrypted %d values", updated)
+}
@@ -6,6 +6,7 @@ import (
 
 	"vigil/internal/config"
 	"vigil/internal/database"
+	"vigil/internal/encryption"
 	"vigil/internal/monitoring"
 	"vigil/internal/server"
 	"vigil/internal/services"
@@ -18,8 +19,17 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Load encryption keys for credentials at rest
+	keyring, err := encryption.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Printf("ENCRYPTION_KEYS is not set; credentials will be stored in cleartext")
+	}
+
 	// Initialize database
-	db, err := database.New(cfg.DatabaseURL)
+	db, err := database.New(cfg.DatabaseURL, keyring)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -233,8 +233,11 @@ class ApiService {
     })
   }
 
-  async getWebhookDeliveries(webhookId: string) {
-    return this.request<any[]>(`/webhooks/${webhookId}/deliveries`)
+  async getWebhookDeliveries(webhookId: string, params: Record<string, string> = {}) {
+    const query = new URLSearchParams(params).toString()
+    return this.request<{ deliveries: any[]; next_cursor: number | null }>(
+      `/webhooks/${webhookId}/deliveries${query ? `?${query}` : ''}`
+    )
   }
 }
 
@@ -13,6 +13,10 @@ type Config struct {
 	JWTSecret   string
 	Environment string
 	Port        string
+
+	// EncryptionKeys is a comma-separated list of version:base64key master keys
+	EncryptionKeys      string
+	EncryptionActiveKey string
 }
 
 // Load loads configuration from environment variables
@@ -26,6 +30,9 @@ func Load() (*Config, error) {
 		JWTSecret:   getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this"),
 		Environment: getEnv("ENVIRONMENT", "development"),
 		Port:        getEnv("PORT", "8080"),
+
+		EncryptionKeys:      getEnv("ENCRYPTION_KEYS", ""),
+		EncryptionActiveKey: getEnv("ENCRYPTION_ACTIVE_KEY", ""),
 	}
 
 	return config, nil
@@ -6,6 +6,9 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"vigil/internal/encryption"
 )
 
 // DB wraps the GORM database instance
@@ -13,8 +16,45 @@ type DB struct {
 	*gorm.DB
 }
 
-// New creates a new database connection
-func New(databaseURL string) (*DB, error) {
+// models are the tables managed by AutoMigrate
+var models = []interface{}{
+	&User{},
+	&Organization{},
+	&OrganizationMember{},
+	&OrganizationSecret{},
+	&Monitor{},
+	&MonitorCheck{},
+	&Alert{},
+	&AlertEvent{},
+	&Incident{},
+	&IncidentUpdate{},
+	&IncidentRule{},
+	&MaintenanceWindow{},
+	&MonitorDependency{},
+	&AlertRule{},
+	&Postmortem{},
+	&PostmortemActionItem{},
+	&NotificationChannel{},
+	&AlertNotification{},
+	&EscalationPolicy{},
+	&EscalationLevel{},
+	&EscalationTarget{},
+	&ContactMethod{},
+	&OnCallSchedule{},
+	&OnCallParticipant{},
+	&OnCallOverride{},
+	&Webhook{},
+	&WebhookDelivery{},
+	&WebhookDeliveryAttempt{},
+	&WebhookDestination{},
+	&WebhookDeliveryTarget{},
+}
+
+// New creates a new database connection. Fields tagged serializer:encrypted are
+// encrypted with keyring, or stored in cleartext when keyring is nil.
+func New(databaseURL string, keyring *encryption.Keyring) (*DB, error) {
+	schema.RegisterSerializer("encrypted", &encryptedSerializer{keyring: keyring})
+
 	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
@@ -23,22 +63,16 @@ func New(databaseURL string) (*DB, error) {
 	}
 
 	// Auto migrate models
-	if err := db.AutoMigrate(
-		&User{},
-		&Organization{},
-		&OrganizationMember{},
-		&Monitor{},
-		&MonitorCheck{},
-		&Alert{},
-		&NotificationChannel{},
-		&AlertNotification{},
-		&Webhook{},
-		&WebhookDelivery{},
-	); err != nil {
+	if err := db.AutoMigrate(models...); err != nil {
 		return nil, err
 	}
 
-	return &DB{db}, nil
+	wrapped := &DB{db}
+	if err := wrapped.backfillIngestTokens(); err != nil {
+		return nil, err
+	}
+
+	return wrapped, nil
 }
 
 // User represents a user in the system
@@ -73,6 +107,17 @@ type OrganizationMember struct {
 	CreatedAt      time.Time    `json:"created_at"`
 }
 
+// OrganizationSecret represents a secret variable that monitors reference as {{secrets.NAME}}
+type OrganizationSecret struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	OrganizationID uint         `json:"organization_id" gorm:"not null;uniqueIndex:idx_organization_secrets_name"`
+	Organization   Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
+	Name           string       `json:"name" gorm:"not null;uniqueIndex:idx_organization_secrets_name"`
+	Value          string       `json:"-" gorm:"not null;serializer:encrypted"` // write-only
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
 // Monitor represents a monitoring target
 type Monitor struct {
 	ID              uint         `json:"id" gorm:"primaryKey"`
@@ -81,13 +126,42 @@ type Monitor struct {
 	Name            string       `json:"name" gorm:"not null"`
 	Type            string       `json:"type" gorm:"not null"` // http, ssl, webhook
 	URL             string       `json:"url" gorm:"not null"`
-	IntervalSeconds int          `json:"interval_seconds" gorm:"default:300"` // 5 minutes
+	Group           string       `json:"group" gorm:"column:group_name;index"` // free-form label, e.g. the load balancer the monitor sits behind
+	Tags            string       `json:"tags"`                                 // comma separated
+	IntervalSeconds int          `json:"interval_seconds" gorm:"default:300"`  // 5 minutes
 	TimeoutSeconds  int          `json:"timeout_seconds" gorm:"default:30"`
 	ExpectedStatus  int          `json:"expected_status" gorm:"default:200"`
-	CustomHeaders   string       `json:"custom_headers"` // JSON string
+	CustomHeaders   string       `json:"custom_headers" gorm:"serializer:encrypted"` // JSON string, values masked in responses
+	Method          string       `json:"method" gorm:"default:'GET'"`
+	RequestBody     string       `json:"request_body"`
+	AuthType        string       `json:"auth_type"` // none, basic, bearer
+	AuthUsername    string       `json:"auth_username"`
+	AuthPassword    string       `json:"-" gorm:"serializer:encrypted"` // never returned
+	AuthToken       string       `json:"-" gorm:"serializer:encrypted"` // never returned
 	IsActive        bool         `json:"is_active" gorm:"default:true"`
-	CreatedAt       time.Time    `json:"created_at"`
-	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// TLS and proxy settings used by http and ssl checks
+	ClientCertificate  string `json:"client_certificate"`            // PEM encoded
+	ClientKey          string `json:"-" gorm:"serializer:encrypted"` // PEM encoded, never returned
+	CACertificates     string `json:"ca_certificates"`               // PEM bundle trusted in addition to system roots
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	ProxyURL           string `json:"proxy_url" gorm:"serializer:encrypted"` // http://, https:// or socks5://, password masked in responses
+
+	EscalationPolicyID *uint `json:"escalation_policy_id"` // nil notifies every active channel once
+
+	// Flap detection: a monitor starts flapping when the share of state changes over its last
+	// FlapWindow checks reaches FlapStartPercent, and stops once it falls to FlapStopPercent.
+	// The thresholds have no column defaults since GORM would swap a stop of 0 for one;
+	// the handler fills them in.
+	FlapDetection    bool       `json:"flap_detection" gorm:"default:true"`
+	FlapWindow       int        `json:"flap_window"`
+	FlapStartPercent float64    `json:"flap_start_percent"`
+	FlapStopPercent  float64    `json:"flap_stop_percent"`
+	IsFlapping       bool       `json:"is_flapping" gorm:"default:false"`
+	FlappingSince    *time.Time `json:"flapping_since"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // MonitorCheck represents a single monitoring check result
@@ -101,18 +175,178 @@ type MonitorCheck struct {
 	ErrorMessage string    `json:"error_message"`
 	ResponseBody string    `json:"response_body"`
 	CheckedAt    time.Time `json:"checked_at" gorm:"not null"`
+
+	InMaintenance bool   `json:"in_maintenance" gorm:"default:false"` // excluded from uptime
+	Reason        string `json:"reason"`                              // dependency_down when a parent monitor was down
+}
+
+// MonitorDependency declares that a monitor depends on a parent monitor, such as the API
+// gateway in front of it. Failures while a parent is down raise no alerts of their own.
+type MonitorDependency struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MonitorID uint      `json:"monitor_id" gorm:"not null;uniqueIndex:idx_monitor_parent"`
+	ParentID  uint      `json:"parent_id" gorm:"not null;uniqueIndex:idx_monitor_parent;index"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
-// Alert represents an alert triggered by a monitor
+// Alert represents an alert triggered by a monitor or webhook
 type Alert struct {
 	ID         uint       `json:"id" gorm:"primaryKey"`
-	MonitorID  uint       `json:"monitor_id" gorm:"not null"`
-	Monitor    Monitor    `json:"monitor" gorm:"foreignKey:MonitorID"`
-	Type       string     `json:"type" gorm:"not null"` // down, ssl_expiring, webhook_failed
+	MonitorID  *uint      `json:"monitor_id"`
+	Monitor    *Monitor   `json:"monitor,omitempty" gorm:"foreignKey:MonitorID"`
+	WebhookID  *uint      `json:"webhook_id"`
+	Webhook    *Webhook   `json:"webhook,omitempty" gorm:"foreignKey:WebhookID"`
+	Type       string     `json:"type" gorm:"not null"` // down, flapping, rule, ssl_expiring, webhook_failed, webhook_destination_failed, webhook_invalid_signature, webhook_dead_letter, webhook_silent, webhook_invalid_payload
 	Message    string     `json:"message" gorm:"not null"`
 	Severity   string     `json:"severity" gorm:"default:'medium'"` // low, medium, high, critical
 	CreatedAt  time.Time  `json:"created_at"`
 	ResolvedAt *time.Time `json:"resolved_at"`
+
+	// Response tracking; an acknowledged alert is no longer re-notified
+	AcknowledgedAt   *time.Time `json:"acknowledged_at"`
+	AcknowledgedByID *uint      `json:"acknowledged_by_id"`
+	AcknowledgedBy   *User      `json:"acknowledged_by,omitempty" gorm:"foreignKey:AcknowledgedByID"`
+	AssignedToID     *uint      `json:"assigned_to_id"`
+	AssignedTo       *User      `json:"assigned_to,omitempty" gorm:"foreignKey:AssignedToID"`
+
+	// Escalation state, copied from the monitor's policy when the alert is raised
+	EscalationPolicyID *uint      `json:"escalation_policy_id"`
+	EscalationLevel    int        `json:"escalation_level" gorm:"default:0"` // position of the last level notified
+	NextEscalationAt   *time.Time `json:"next_escalation_at" gorm:"index"`
+
+	IncidentID           *uint `json:"incident_id" gorm:"index"`
+	AlertRuleID          *uint `json:"alert_rule_id" gorm:"index"`          // set on alerts of type rule
+	WebhookDestinationID *uint `json:"webhook_destination_id" gorm:"index"` // set on alerts of type webhook_destination_failed
+}
+
+// AlertEvent is an entry on an alert's timeline
+type AlertEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AlertID    uint      `json:"alert_id" gorm:"not null;index"`
+	Type       string    `json:"type" gorm:"not null"` // created, acknowledged, assigned, unassigned, note, escalated, reminded, grouped, resolved
+	ActorID    *uint     `json:"actor_id"`             // nil for events raised by Vigil
+	Actor      *User     `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+	AssigneeID *uint     `json:"assignee_id"`
+	Assignee   *User     `json:"assignee,omitempty" gorm:"foreignKey:AssigneeID"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// MaintenanceWindow is planned downtime during which monitors keep being checked but raise
+// no alerts or notifications
+type MaintenanceWindow struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID  uint      `json:"organization_id" gorm:"not null;index"`
+	Name            string    `json:"name" gorm:"not null"`
+	Description     string    `json:"description"`
+	StartsAt        time.Time `json:"starts_at" gorm:"not null"`        // start of the first occurrence
+	DurationMinutes int       `json:"duration_minutes" gorm:"not null"` // length of each occurrence
+	Timezone        string    `json:"timezone" gorm:"default:'UTC'"`    // IANA name the recurrence is evaluated in
+	RRule           string    `json:"rrule"`                            // RFC 5545 recurrence, e.g. FREQ=WEEKLY;BYDAY=TU; empty for one-off
+	Tags            string    `json:"tags"`                             // comma separated; covers monitors with any of these tags
+	Monitors        []Monitor `json:"monitors" gorm:"many2many:maintenance_window_monitors"`
+	CreatedByID     uint      `json:"created_by_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// AlertRule raises an alert when a condition over a monitor's recent checks holds, e.g.
+// "p95 response_time > 1500 over 10 minutes" or "3 of the last 5 checks have status_code >= 500"
+type AlertRule struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;index"`
+	MonitorID      *uint     `json:"monitor_id" gorm:"index"` // the rule covers one monitor, or
+	Tags           string    `json:"tags"`                    // every monitor with any of these comma separated tags
+	Name           string    `json:"name" gorm:"not null"`
+	Field          string    `json:"field" gorm:"not null"`    // response_time, status_code, status, error_message
+	Aggregate      string    `json:"aggregate"`                // p50, p90, p95, p99, avg, min, max; empty compares each check
+	Operator       string    `json:"operator" gorm:"not null"` // gt, gte, lt, lte, eq, ne, contains
+	Value          string    `json:"value" gorm:"not null"`
+	WindowMinutes  int       `json:"window_minutes"`               // checks from the last N minutes, or
+	WindowChecks   int       `json:"window_checks"`                // the last N checks; just the latest check when neither is set
+	MinMatches     int       `json:"min_matches" gorm:"default:1"` // matching checks needed when comparing each check
+	Severity       string    `json:"severity" gorm:"default:'medium'"`
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Incident groups related alerts so they are handled, and notified, as one
+type Incident struct {
+	ID             uint             `json:"id" gorm:"primaryKey"`
+	OrganizationID uint             `json:"organization_id" gorm:"not null;index"`
+	Title          string           `json:"title" gorm:"not null"`
+	Status         string           `json:"status" gorm:"default:'investigating';index"` // investigating, identified, monitoring, resolved
+	Severity       string           `json:"severity" gorm:"default:'medium'"`            // highest severity of its alerts
+	RuleID         *uint            `json:"rule_id"`
+	GroupKey       string           `json:"group_key" gorm:"index"` // e.g. host:api.example.com, group:edge-lb, time:<rule id>
+	StartedAt      time.Time        `json:"started_at"`
+	LastAlertAt    time.Time        `json:"last_alert_at"`
+	ResolvedAt     *time.Time       `json:"resolved_at"`
+	Alerts         []Alert          `json:"alerts,omitempty" gorm:"foreignKey:IncidentID"`
+	Updates        []IncidentUpdate `json:"updates,omitempty" gorm:"foreignKey:IncidentID"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// IncidentUpdate records a status change or message posted on an incident
+type IncidentUpdate struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	IncidentID uint      `json:"incident_id" gorm:"not null;index"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message"`
+	AuthorID   *uint     `json:"author_id"` // nil for updates made by Vigil
+	Author     *User     `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Postmortem is the write-up of an incident or alert. Its timeline is generated from the
+// checks, alerts and notifications in its window.
+type Postmortem struct {
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	OrganizationID uint                   `json:"organization_id" gorm:"not null;index"`
+	IncidentID     *uint                  `json:"incident_id" gorm:"index"` // set for incident postmortems
+	AlertID        *uint                  `json:"alert_id" gorm:"index"`    // set for single alert postmortems
+	Title          string                 `json:"title" gorm:"not null"`
+	Status         string                 `json:"status" gorm:"default:'draft'"` // draft, published
+	AuthorID       uint                   `json:"author_id"`
+	Author         *User                  `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	WindowStart    time.Time              `json:"window_start"`
+	WindowEnd      time.Time              `json:"window_end"`
+	Summary        string                 `json:"summary"` // Markdown
+	Impact         string                 `json:"impact"`
+	RootCause      string                 `json:"root_cause"`
+	Resolution     string                 `json:"resolution"`
+	LessonsLearned string                 `json:"lessons_learned"`
+	ActionItems    []PostmortemActionItem `json:"action_items,omitempty" gorm:"foreignKey:PostmortemID"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// PostmortemActionItem is a follow-up task from a postmortem
+type PostmortemActionItem struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	PostmortemID uint       `json:"postmortem_id" gorm:"not null;index"`
+	Description  string     `json:"description" gorm:"not null"`
+	OwnerID      *uint      `json:"owner_id"`
+	Owner        *User      `json:"owner,omitempty" gorm:"foreignKey:OwnerID"`
+	DueDate      *time.Time `json:"due_date"`
+	Status       string     `json:"status" gorm:"default:'open'"` // open, done
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IncidentRule decides which new alerts join an open incident instead of opening their own
+type IncidentRule struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;index"`
+	Name           string    `json:"name" gorm:"not null"`
+	GroupBy        string    `json:"group_by" gorm:"not null"`         // host, group, time
+	WindowMinutes  int       `json:"window_minutes" gorm:"default:10"` // how long after the last alert an incident keeps absorbing new ones
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // NotificationChannel represents a notification channel
@@ -120,23 +354,116 @@ type NotificationChannel struct {
 	ID             uint         `json:"id" gorm:"primaryKey"`
 	OrganizationID uint         `json:"organization_id" gorm:"not null"`
 	Organization   Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
-	Type           string       `json:"type" gorm:"not null"` // email, slack, discord, webhook
-	Config         string       `json:"config"`               // JSON string
+	Type           string       `json:"type" gorm:"not null"`               // email, slack, discord, webhook, on_call
+	Config         string       `json:"config" gorm:"serializer:encrypted"` // JSON string
 	IsActive       bool         `json:"is_active" gorm:"default:true"`
 	CreatedAt      time.Time    `json:"created_at"`
 	UpdatedAt      time.Time    `json:"updated_at"`
+
+	// Reminders re-send notifications for alerts left unresolved and unacknowledged
+	ReminderIntervalMinutes int `json:"reminder_interval_minutes"` // 0 turns reminders off
+	MaxReminders            int `json:"max_reminders"`
 }
 
 // AlertNotification represents a notification sent for an alert
 type AlertNotification struct {
-	ID                    uint                `json:"id" gorm:"primaryKey"`
-	AlertID               uint                `json:"alert_id" gorm:"not null"`
-	Alert                 Alert               `json:"alert" gorm:"foreignKey:AlertID"`
-	NotificationChannelID uint                `json:"notification_channel_id" gorm:"not null"`
-	NotificationChannel   NotificationChannel `json:"notification_channel" gorm:"foreignKey:NotificationChannelID"`
-	SentAt                time.Time           `json:"sent_at"`
-	Status                string              `json:"status" gorm:"default:'pending'"` // pending, sent, failed
-	ErrorMessage          string              `json:"error_message"`
+	ID                    uint                 `json:"id" gorm:"primaryKey"`
+	AlertID               uint                 `json:"alert_id" gorm:"not null"`
+	Alert                 Alert                `json:"alert" gorm:"foreignKey:AlertID"`
+	NotificationChannelID *uint                `json:"notification_channel_id"` // set for channel notifications
+	NotificationChannel   *NotificationChannel `json:"notification_channel,omitempty" gorm:"foreignKey:NotificationChannelID"`
+	UserID                *uint                `json:"user_id"` // set for notifications sent directly to a user
+	User                  *User                `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	ContactMethodID       *uint                `json:"contact_method_id"`                 // nil when a user without contact methods was emailed
+	EscalationLevel       int                  `json:"escalation_level" gorm:"default:0"` // 0 when sent outside an escalation policy
+	ReminderNumber        int                  `json:"reminder_number" gorm:"default:0"`  // 0 for the first notification, then 1, 2, ...
+	SentAt                time.Time            `json:"sent_at"`
+	Status                string               `json:"status" gorm:"default:'pending'"` // pending, sent, failed
+	ErrorMessage          string               `json:"error_message"`
+}
+
+// EscalationPolicy describes who is notified, and when, while an alert stays unacknowledged
+type EscalationPolicy struct {
+	ID             uint              `json:"id" gorm:"primaryKey"`
+	OrganizationID uint              `json:"organization_id" gorm:"not null;index"`
+	Organization   Organization      `json:"-" gorm:"foreignKey:OrganizationID"`
+	Name           string            `json:"name" gorm:"not null"`
+	Description    string            `json:"description"`
+	Levels         []EscalationLevel `json:"levels" gorm:"foreignKey:EscalationPolicyID"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+
+	// Reminders re-notify the level reached while an alert stays unacknowledged
+	ReminderIntervalMinutes int `json:"reminder_interval_minutes"` // 0 turns reminders off
+	MaxReminders            int `json:"max_reminders"`
+}
+
+// EscalationLevel is one step of an escalation policy
+type EscalationLevel struct {
+	ID                 uint               `json:"id" gorm:"primaryKey"`
+	EscalationPolicyID uint               `json:"escalation_policy_id" gorm:"not null;index"`
+	Position           int                `json:"position" gorm:"not null"`      // 1-based order within the policy
+	DelayMinutes       int                `json:"delay_minutes" gorm:"not null"` // wait after the previous level, or after the alert for the first
+	Targets            []EscalationTarget `json:"targets" gorm:"foreignKey:EscalationLevelID"`
+}
+
+// EscalationTarget is a recipient notified when an escalation level fires
+type EscalationTarget struct {
+	ID                    uint   `json:"id" gorm:"primaryKey"`
+	EscalationLevelID     uint   `json:"escalation_level_id" gorm:"not null;index"`
+	Type                  string `json:"type" gorm:"not null"` // channel, user, schedule
+	NotificationChannelID *uint  `json:"notification_channel_id"`
+	UserID                *uint  `json:"user_id"`
+	ScheduleID            *uint  `json:"schedule_id"` // notifies whoever is on call when the level fires
+}
+
+// ContactMethod is a way to reach a user directly when they are paged
+type ContactMethod struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Type      string    `json:"type" gorm:"not null"`                // email, slack, discord, webhook
+	Address   string    `json:"address" gorm:"serializer:encrypted"` // email address or URL
+	Label     string    `json:"label"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OnCallSchedule rotates on-call duty among organization members
+type OnCallSchedule struct {
+	ID             uint                `json:"id" gorm:"primaryKey"`
+	OrganizationID uint                `json:"organization_id" gorm:"not null;index"`
+	Organization   Organization        `json:"-" gorm:"foreignKey:OrganizationID"`
+	Name           string              `json:"name" gorm:"not null"`
+	Description    string              `json:"description"`
+	Timezone       string              `json:"timezone" gorm:"default:'UTC'"`         // IANA name; handoffs keep their local time across DST
+	RotationType   string              `json:"rotation_type" gorm:"default:'weekly'"` // daily, weekly
+	RotationStart  time.Time           `json:"rotation_start"`                        // first handoff; later ones repeat at its local time of day
+	Participants   []OnCallParticipant `json:"participants" gorm:"foreignKey:ScheduleID"`
+	Overrides      []OnCallOverride    `json:"overrides" gorm:"foreignKey:ScheduleID"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// OnCallParticipant is a member's place in a schedule's rotation
+type OnCallParticipant struct {
+	ID         uint  `json:"id" gorm:"primaryKey"`
+	ScheduleID uint  `json:"schedule_id" gorm:"not null;index"`
+	Position   int   `json:"position" gorm:"not null"` // 1-based order of the rotation
+	UserID     uint  `json:"user_id" gorm:"not null"`
+	User       *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// OnCallOverride puts someone else on call for a period, e.g. to cover a shift
+type OnCallOverride struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ScheduleID  uint      `json:"schedule_id" gorm:"not null;index"`
+	UserID      uint      `json:"user_id" gorm:"not null"`
+	User        *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	StartsAt    time.Time `json:"starts_at" gorm:"not null"`
+	EndsAt      time.Time `json:"ends_at" gorm:"not null;index"`
+	Reason      string    `json:"reason"`
+	CreatedByID uint      `json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // Webhook represents an incoming webhook to monitor
@@ -146,22 +473,130 @@ type Webhook struct {
 	Organization   Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
 	Name           string       `json:"name" gorm:"not null"`
 	URL            string       `json:"url" gorm:"not null"`
-	Secret         string       `json:"secret"`
+	Secret         string       `json:"secret" gorm:"serializer:encrypted"`
 	RetryCount     int          `json:"retry_count" gorm:"default:3"`
 	TimeoutSeconds int          `json:"timeout_seconds" gorm:"default:30"`
 	IsActive       bool         `json:"is_active" gorm:"default:true"`
-	CreatedAt      time.Time    `json:"created_at"`
-	UpdatedAt      time.Time    `json:"updated_at"`
+	ForwardHeaders string       `json:"forward_headers"` // comma-separated request headers forwarded to URL, defaults to Content-Type
+
+	// Public receiver URL token; the previous token keeps working until it expires
+	IngestToken                  string     `json:"ingest_token" gorm:"uniqueIndex"`
+	PreviousIngestToken          string     `json:"-" gorm:"index"`
+	PreviousIngestTokenExpiresAt *time.Time `json:"previous_ingest_token_expires_at"`
+
+	// Abuse protection on the public receiver
+	RateLimitPerMinute int    `json:"rate_limit_per_minute"` // 0 disables
+	MaxPayloadBytes    int    `json:"max_payload_bytes"`     // 0 uses the server limit
+	AllowedSourceCIDRs string `json:"allowed_source_cidrs"`  // comma-separated IPs or CIDRs, empty allows all
+
+	DeadLetterAlertThreshold int `json:"dead_letter_alert_threshold"` // 0 disables
+
+	// Expected cadence of incoming deliveries, alerts when the provider goes silent
+	ExpectedCadenceMinutes int        `json:"expected_cadence_minutes" gorm:"default:0"`   // 0 disables
+	BusinessHoursOnly      bool       `json:"business_hours_only"`                         // only count time inside business hours
+	BusinessHoursStart     string     `json:"business_hours_start" gorm:"default:'09:00'"` // HH:MM
+	BusinessHoursEnd       string     `json:"business_hours_end" gorm:"default:'17:00'"`   // HH:MM
+	BusinessDays           string     `json:"business_days" gorm:"default:'1,2,3,4,5'"`    // comma-separated weekdays, 0 is Sunday
+	Timezone               string     `json:"timezone" gorm:"default:'UTC'"`
+	LastReceivedAt         *time.Time `json:"last_received_at"`
+
+	// JSON Schema validation of incoming payloads
+	JSONSchema                 string `json:"json_schema"`                   // JSON Schema document, empty disables validation
+	RejectInvalidPayloads      bool   `json:"reject_invalid_payloads"`       // reject with 422 instead of flagging the delivery
+	InvalidPayloadAlertPercent int    `json:"invalid_payload_alert_percent"` // per 10 minutes, 0 disables
+
+	// Mock mode: the receiver answers with the configured response and captures deliveries
+	// for inspection instead of forwarding them
+	MockEnabled           bool   `json:"mock_enabled"`
+	MockStatusCode        int    `json:"mock_status_code" gorm:"default:200"`
+	MockHeaders           string `json:"mock_headers"` // JSON object
+	MockBody              string `json:"mock_body"`
+	MockDelayMs           int    `json:"mock_delay_ms" gorm:"default:0"`
+	MockFailureRate       int    `json:"mock_failure_rate" gorm:"default:0"` // percent of requests answered with MockFailureStatusCode
+	MockFailureStatusCode int    `json:"mock_failure_status_code" gorm:"default:500"`
+
+	// Signature verification for incoming deliveries
+	VerificationScheme             string `json:"verification_scheme" gorm:"default:'none'"` // none, hmac_sha256, github, stripe, slack
+	SignatureHeader                string `json:"signature_header"`                          // header checked by hmac_sha256, defaults to X-Signature
+	RejectInvalidSignatures        bool   `json:"reject_invalid_signatures"`                 // reject with 401 instead of flagging the delivery
+	SignatureToleranceSeconds      int    `json:"signature_tolerance_seconds" gorm:"default:300"`
+	InvalidSignatureAlertThreshold int    `json:"invalid_signature_alert_threshold"` // per 10 minutes, 0 disables
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // WebhookDelivery represents a webhook delivery attempt
 type WebhookDelivery struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	WebhookID    uint      `json:"webhook_id" gorm:"not null"`
-	Webhook      Webhook   `json:"webhook" gorm:"foreignKey:WebhookID"`
-	Payload      string    `json:"payload"`                // JSON string
-	Status       string    `json:"status" gorm:"not null"` // pending, success, failed
-	ResponseCode int       `json:"response_code"`
-	DeliveredAt  time.Time `json:"delivered_at"`
-	RetryCount   int       `json:"retry_count" gorm:"default:0"`
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index:idx_webhook_deliveries_webhook_delivered_at,priority:1"`
+	Webhook        Webhook    `json:"webhook" gorm:"foreignKey:WebhookID"`
+	Payload        string     `json:"payload"`         // JSON string
+	RequestHeaders string     `json:"request_headers"` // JSON object of the received headers
+	Method         string     `json:"method"`
+	QueryString    string     `json:"query_string"`
+	SourceIP       string     `json:"source_ip"`
+	Status         string     `json:"status" gorm:"not null"` // pending, delivering, retrying, success, failed, dead_letter, rejected, captured
+	ResponseCode   int        `json:"response_code"`
+	DeliveredAt    time.Time  `json:"delivered_at" gorm:"index:idx_webhook_deliveries_webhook_delivered_at,priority:2"`
+	RetryCount     int        `json:"retry_count" gorm:"default:0"`
+	LastError      string     `json:"last_error"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at" gorm:"index"`
+	TargetURL      string     `json:"target_url"` // replay override, empty uses the webhook URL
+	ReplayCount    int        `json:"replay_count" gorm:"default:0"`
+	DeadLetteredAt *time.Time `json:"dead_lettered_at"`
+
+	SignatureStatus string `json:"signature_status" gorm:"default:'unverified'"` // unverified, valid, invalid
+	SignatureError  string `json:"signature_error"`
+
+	ValidationStatus string `json:"validation_status" gorm:"default:'unchecked'"` // unchecked, valid, invalid
+	ValidationErrors string `json:"validation_errors"`                            // JSON array of schema violations
+
+	Attempts []WebhookDeliveryAttempt `json:"attempts,omitempty" gorm:"foreignKey:WebhookDeliveryID"`
+	Targets  []WebhookDeliveryTarget  `json:"targets,omitempty" gorm:"foreignKey:WebhookDeliveryID"`
+}
+
+// WebhookDeliveryAttempt represents a single forward attempt for a webhook delivery
+type WebhookDeliveryAttempt struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	WebhookDeliveryID uint      `json:"webhook_delivery_id" gorm:"not null;index"`
+	TargetID          *uint     `json:"target_id" gorm:"index"` // set for attempts to an additional destination
+	AttemptNumber     int       `json:"attempt_number"`
+	URL               string    `json:"url"`
+	Replay            bool      `json:"replay"`
+	AttemptedAt       time.Time `json:"attempted_at" gorm:"not null"`
+	DurationMs        int       `json:"duration_ms"`
+	StatusCode        int       `json:"status_code"`
+	ResponseHeaders   string    `json:"response_headers"` // JSON object, values truncated
+	ResponseBody      string    `json:"response_body"`    // truncated
+	Error             string    `json:"error"`
+}
+
+// WebhookDestination is an additional target a webhook's deliveries fan out to
+type WebhookDestination struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	WebhookID         uint      `json:"webhook_id" gorm:"not null;index"`
+	Name              string    `json:"name" gorm:"not null"`
+	URL               string    `json:"url" gorm:"not null"`
+	FilterExpression  string    `json:"filter_expression"`  // empty forwards every delivery
+	TransformTemplate string    `json:"transform_template"` // Go text/template producing the body, empty forwards the payload
+	IsActive          bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryTarget tracks forwarding of one delivery to one destination
+type WebhookDeliveryTarget struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	WebhookDeliveryID uint       `json:"webhook_delivery_id" gorm:"not null;index"`
+	DestinationID     uint       `json:"destination_id" gorm:"not null;index"`
+	URL               string     `json:"url"`
+	Status            string     `json:"status" gorm:"not null"` // pending, delivering, retrying, success, failed, filtered
+	ResponseCode      int        `json:"response_code"`
+	RetryCount        int        `json:"retry_count" gorm:"default:0"`
+	LastError         string     `json:"last_error"`
+	LastAttemptAt     *time.Time `json:"last_attempt_at"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at" gorm:"index"`
+	CreatedAt         time.Time  `json:"created_at"`
 }
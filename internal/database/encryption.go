@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+
+	"vigil/internal/encryption"
+)
+
+// encryptedColumns lists the columns stored with the "encrypted" serializer, by table
+var encryptedColumns = map[string][]string{
+	"monitors":              {"custom_headers", "client_key", "auth_password", "auth_token", "proxy_url"},
+	"webhooks":              {"secret"},
+	"notification_channels": {"config"},
+	"contact_methods":       {"address"},
+	"organization_secrets":  {"value"},
+}
+
+// encryptedSerializer transparently encrypts string fields tagged with serializer:encrypted
+type encryptedSerializer struct {
+	keyring *encryption.Keyring
+}
+
+// Scan decrypts a column value into the field
+func (s *encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported value type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	if encryption.IsEncrypted(value) {
+		if s.keyring == nil {
+			return fmt.Errorf("field %s is encrypted but no encryption keys are configured", field.Name)
+		}
+
+		plaintext, err := s.keyring.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+		}
+		value = plaintext
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+// Value encrypts the field value before it is written
+func (s *encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+
+	// Without keys, or for empty values, store the value as is
+	if s.keyring == nil || value == "" {
+		return value, nil
+	}
+
+	return s.keyring.Encrypt(value)
+}
+
+// Reencrypt rewrites every encrypted column that is not already under the active key,
+// including legacy cleartext values. Rows are updated one at a time with a
+// compare-and-swap on the old value, so it is safe to run while servers are writing.
+func (db *DB) Reencrypt(keyring *encryption.Keyring, batchSize int) (int, error) {
+	if keyring == nil {
+		return 0, errors.New("no encryption keys configured")
+	}
+
+	updated := 0
+	for table, columns := range encryptedColumns {
+		for _, column := range columns {
+			count, err := db.reencryptColumn(keyring, table, column, batchSize)
+			updated += count
+			if err != nil {
+				return updated, fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+// reencryptColumn re-encrypts a single column in batches ordered by primary key
+func (db *DB) reencryptColumn(keyring *encryption.Keyring, table, column string, batchSize int) (int, error) {
+	type row struct {
+		ID    uint
+		Value string
+	}
+
+	updated := 0
+	var lastID uint
+	for {
+		var rows []row
+		if err := db.Table(table).
+			Select(fmt.Sprintf("id, %s AS value", column)).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(batchSize).
+			Scan(&rows).Error; err != nil {
+			return updated, err
+		}
+
+		if len(rows) == 0 {
+			return updated, nil
+		}
+
+		for _, r := range rows {
+			lastID = r.ID
+
+			ciphertext, changed, err := reencryptValue(keyring, r.Value)
+			if err != nil {
+				return updated, fmt.Errorf("row %d: %w", r.ID, err)
+			}
+			if !changed {
+				continue
+			}
+
+			// Leave the row alone if it changed since it was read so concurrent writes
+			// are never overwritten; a later run picks it up if needed
+			result := db.Table(table).
+				Where(fmt.Sprintf("id = ? AND %s = ?", column), r.ID, r.Value).
+				Update(column, ciphertext)
+			if result.Error != nil {
+				return updated, fmt.Errorf("row %d: %w", r.ID, result.Error)
+			}
+			updated += int(result.RowsAffected)
+		}
+	}
+}
+
+// reencryptValue returns a stored value encrypted under the active key. Empty values and
+// values already under the active key are left alone, reported by changed being false.
+func reencryptValue(keyring *encryption.Keyring, value string) (ciphertext string, changed bool, err error) {
+	if value == "" {
+		return value, false, nil
+	}
+	if version, ok := encryption.KeyVersion(value); ok && version == keyring.ActiveVersion() {
+		return value, false, nil
+	}
+
+	plaintext, err := keyring.Decrypt(value)
+	if err != nil {
+		return "", false, err
+	}
+
+	ciphertext, err = keyring.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return ciphertext, true, nil
+}
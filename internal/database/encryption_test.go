@@ -0,0 +1,205 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+
+	"vigil/internal/encryption"
+)
+
+// credential is a minimal model for exercising the serializer
+type credential struct {
+	ID    uint
+	Value string
+}
+
+func testKeyring(t *testing.T, spec, active string) *encryption.Keyring {
+	t.Helper()
+	keyring, err := encryption.ParseKeyring(spec, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func testKeySpec(version string, b byte) string {
+	return version + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+// scanValue runs the serializer's Scan for a stored value and returns the field contents
+func scanValue(t *testing.T, serializer *encryptedSerializer, stored interface{}) (string, error) {
+	t.Helper()
+	parsed, err := schema.Parse(&credential{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst credential
+	err = serializer.Scan(context.Background(), parsed.LookUpField("Value"), reflect.ValueOf(&dst), stored)
+	return dst.Value, err
+}
+
+func TestEncryptedSerializer(t *testing.T) {
+	keyring := testKeyring(t, testKeySpec("v1", 1), "v1")
+	ciphertext, err := keyring.Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		serializer *encryptedSerializer
+		stored     interface{}
+		want       string
+		wantErr    bool
+	}{
+		{name: "ciphertext is decrypted", serializer: &encryptedSerializer{keyring: keyring}, stored: ciphertext, want: "hunter2"},
+		{name: "ciphertext as bytes", serializer: &encryptedSerializer{keyring: keyring}, stored: []byte(ciphertext), want: "hunter2"},
+		{name: "legacy cleartext passes through", serializer: &encryptedSerializer{keyring: keyring}, stored: "legacy-password", want: "legacy-password"},
+		{name: "cleartext without keys", serializer: &encryptedSerializer{}, stored: "legacy-password", want: "legacy-password"},
+		{name: "null is empty", serializer: &encryptedSerializer{keyring: keyring}, stored: nil, want: ""},
+		{name: "ciphertext without keys", serializer: &encryptedSerializer{}, stored: ciphertext, wantErr: true},
+		{name: "unsupported type", serializer: &encryptedSerializer{keyring: keyring}, stored: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scanValue(t, tt.serializer, tt.stored)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan succeeded with %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Scan = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptedSerializerValue(t *testing.T) {
+	keyring := testKeyring(t, testKeySpec("v1", 1), "v1")
+	ctx := context.Background()
+
+	stored, err := (&encryptedSerializer{keyring: keyring}).Value(ctx, nil, reflect.Value{}, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.(string), "vigil:enc:v1:") {
+		t.Errorf("stored value %q is not encrypted under v1", stored)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		serializer *encryptedSerializer
+		value      string
+	}{
+		{name: "empty values stay empty", serializer: &encryptedSerializer{keyring: keyring}, value: ""},
+		{name: "no keys stores cleartext", serializer: &encryptedSerializer{}, value: "hunter2"},
+	} {
+		stored, err := tt.serializer.Value(ctx, nil, reflect.Value{}, tt.value)
+		if err != nil || stored != tt.value {
+			t.Errorf("%s: Value = %v, %v; want %q", tt.name, stored, err, tt.value)
+		}
+	}
+}
+
+func TestReencryptValue(t *testing.T) {
+	old := testKeyring(t, testKeySpec("v1", 1), "v1")
+	rotated := testKeyring(t, testKeySpec("v1", 1)+","+testKeySpec("v2", 2), "v2")
+
+	underOld, _ := old.Encrypt("secret")
+	underActive, _ := rotated.Encrypt("secret")
+
+	tests := []struct {
+		name        string
+		value       string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{name: "empty is skipped", value: ""},
+		{name: "active version is skipped", value: underActive},
+		{name: "retired version is rewritten", value: underOld, wantChanged: true},
+		{name: "legacy cleartext is encrypted", value: "secret", wantChanged: true},
+		{name: "unknown version fails", value: "vigil:enc:v0:AAAA:AAAA", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, changed, err := reencryptValue(rotated, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != tt.wantChanged {
+				t.Fatalf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !changed {
+				return
+			}
+			if version, _ := encryption.KeyVersion(ciphertext); version != "v2" {
+				t.Errorf("rewritten under %q, want v2", version)
+			}
+			if plaintext, err := rotated.Decrypt(ciphertext); err != nil || plaintext != "secret" {
+				t.Errorf("rewritten value decrypts to %q, %v", plaintext, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedColumnsCoverEveryModel(t *testing.T) {
+	schema.RegisterSerializer("encrypted", &encryptedSerializer{})
+
+	tagged := map[string][]string{}
+	cache := &sync.Map{}
+	for _, model := range models {
+		parsed, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		for _, field := range parsed.Fields {
+			if field.TagSettings["SERIALIZER"] == "encrypted" {
+				tagged[parsed.Table] = append(tagged[parsed.Table], field.DBName)
+			}
+		}
+	}
+
+	// Re-encryption only rewrites the listed columns, so a missing entry leaves that
+	// column under a retired key
+	for table, columns := range tagged {
+		for _, column := range columns {
+			if !containsColumn(encryptedColumns[table], column) {
+				t.Errorf("%s.%s is tagged serializer:encrypted but missing from encryptedColumns", table, column)
+			}
+		}
+	}
+	for table, columns := range encryptedColumns {
+		for _, column := range columns {
+			if !containsColumn(tagged[table], column) {
+				t.Errorf("encryptedColumns lists %s.%s, which is not an encrypted model field", table, column)
+			}
+		}
+	}
+}
+
+func containsColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
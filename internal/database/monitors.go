@@ -0,0 +1,86 @@
+package database
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// MaskedValue stands in for credentials in API responses
+const MaskedValue = "********"
+
+// secretReference matches {{secrets.NAME}} placeholders, which are safe to return
+var secretReference = regexp.MustCompile(`\{\{\s*secrets\.[A-Za-z0-9_]+\s*\}\}`)
+
+// MarshalJSON masks literal credentials left in custom headers; placeholders are kept
+func (m Monitor) MarshalJSON() ([]byte, error) {
+	type monitorJSON Monitor
+	masked := monitorJSON(m)
+	masked.CustomHeaders = MaskCustomHeaders(m.CustomHeaders)
+	masked.ProxyURL = MaskProxyURL(m.ProxyURL)
+	return json.Marshal(masked)
+}
+
+// MaskProxyURL masks the password in a proxy URL's userinfo unless it only references
+// secrets. The URL is not parsed since placeholders are not valid userinfo.
+func MaskProxyURL(proxyURL string) string {
+	scheme := strings.Index(proxyURL, "://")
+	if scheme < 0 {
+		return proxyURL
+	}
+	authority := proxyURL[scheme+3:]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return proxyURL
+	}
+	username, password, hasPassword := strings.Cut(authority[:at], ":")
+	if !hasPassword || onlySecretReferences(password) {
+		return proxyURL
+	}
+
+	return proxyURL[:scheme+3] + username + ":" + MaskedValue + proxyURL[scheme+3+at:]
+}
+
+// MaskCustomHeaders masks every value of a JSON header map unless it only references
+// secrets, optionally behind an auth scheme such as "Bearer {{secrets.TOKEN}}"
+func MaskCustomHeaders(headers string) string {
+	if headers == "" {
+		return ""
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(headers), &values); err != nil {
+		return MaskedValue
+	}
+
+	for name, value := range values {
+		if !onlySecretReferences(value) {
+			values[name] = MaskedValue
+		}
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return MaskedValue
+	}
+	return string(encoded)
+}
+
+// onlySecretReferences reports whether a header value holds nothing but secret
+// placeholders and an optional auth scheme
+func onlySecretReferences(value string) bool {
+	if !secretReference.MatchString(value) {
+		return false
+	}
+
+	rest := strings.TrimSpace(secretReference.ReplaceAllString(value, ""))
+	switch strings.ToLower(rest) {
+	case "", "bearer", "basic", "token":
+		return true
+	}
+	return false
+}
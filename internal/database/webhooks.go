@@ -0,0 +1,54 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"gorm.io/gorm"
+)
+
+// ingestTokenBytes is the amount of randomness in a webhook ingest token
+const ingestTokenBytes = 24
+
+// NewIngestToken returns a random, URL-safe token for a webhook's public receiver URL
+func NewIngestToken() (string, error) {
+	buf := make([]byte, ingestTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whk_" + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// BeforeCreate gives new webhooks an ingest token
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.IngestToken != "" {
+		return nil
+	}
+
+	token, err := NewIngestToken()
+	if err != nil {
+		return err
+	}
+	w.IngestToken = token
+	return nil
+}
+
+// backfillIngestTokens gives webhooks created before ingest tokens existed a token
+func (db *DB) backfillIngestTokens() error {
+	var ids []uint
+	if err := db.Model(&Webhook{}).Where("ingest_token IS NULL OR ingest_token = ''").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		token, err := NewIngestToken()
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&Webhook{}).Where("id = ?", id).Update("ingest_token", token).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
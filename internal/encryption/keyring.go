@@ -0,0 +1,187 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prefix marks a value as envelope-encrypted ciphertext
+const prefix = "vigil:enc:"
+
+// keySize is the required master key length (AES-256)
+const keySize = 32
+
+// Keyring holds the master keys used for envelope encryption, indexed by version
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewKeyring creates a keyring that encrypts with the active key version
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key version %q is not configured", active)
+	}
+
+	for version, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key version %q must be %d bytes, got %d", version, keySize, len(key))
+		}
+	}
+
+	return &Keyring{active: active, keys: keys}, nil
+}
+
+// ParseKeyring builds a keyring from a "version:base64key,..." list. It returns nil
+// when no keys are configured, in which case values are stored in cleartext.
+func ParseKeyring(spec, active string) (*Keyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		version, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || version == "" {
+			return nil, fmt.Errorf("invalid key entry %q, expected version:base64key", entry)
+		}
+		if strings.Contains(version, ":") {
+			return nil, fmt.Errorf("key version %q must not contain ':'", version)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 for key version %q: %w", version, err)
+		}
+		keys[version] = key
+	}
+
+	return NewKeyring(active, keys)
+}
+
+// ActiveVersion returns the key version used for new ciphertexts
+func (k *Keyring) ActiveVersion() string {
+	return k.active
+}
+
+// IsEncrypted reports whether a stored value is ciphertext produced by a keyring
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyVersion returns the key version a ciphertext was encrypted under
+func KeyVersion(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+	version, _, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return version, ok
+}
+
+// Encrypt seals plaintext under a fresh data key, which is itself wrapped by the active
+// master key. The result has the form vigil:enc:<version>:<wrapped key>:<ciphertext>.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(k.keys[k.active], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + k.active + ":" +
+		base64.StdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt. Values without the ciphertext prefix
+// are returned unchanged so rows written before encryption was enabled stay readable.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed ciphertext")
+	}
+
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("unknown key version %q", parts[0])
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed wrapped key: %w", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	dataKey, err := open(masterKey, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := open(dataKey, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// seal encrypts data with AES-GCM, prefixing the random nonce
+func seal(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts data produced by seal
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// newGCM creates an AES-GCM cipher for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
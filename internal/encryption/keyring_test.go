@@ -0,0 +1,198 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// testKey returns a valid base64 key filled with b
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func mustKeyring(t *testing.T, spec, active string) *Keyring {
+	t.Helper()
+	keyring, err := ParseKeyring(spec, active)
+	if err != nil {
+		t.Fatalf("ParseKeyring(%q, %q): %v", spec, active, err)
+	}
+	return keyring
+}
+
+func TestParseKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		active  string
+		wantErr string
+		wantNil bool
+	}{
+		{name: "empty spec disables encryption", spec: "  ", active: "v1", wantNil: true},
+		{name: "single key", spec: "v1:" + testKey(1), active: "v1"},
+		{name: "several keys with spaces", spec: "v1:" + testKey(1) + ", v2:" + testKey(2), active: "v2"},
+		{name: "missing separator", spec: "v1" + testKey(1), active: "v1", wantErr: "expected version:base64key"},
+		{name: "empty version", spec: ":" + testKey(1), active: "v1", wantErr: "expected version:base64key"},
+		{name: "bad base64", spec: "v1:not-base64!", active: "v1", wantErr: "invalid base64"},
+		{name: "short key", spec: "v1:" + base64.StdEncoding.EncodeToString([]byte("short")), active: "v1", wantErr: "must be 32 bytes"},
+		{name: "unknown active version", spec: "v1:" + testKey(1), active: "v2", wantErr: "is not configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.spec, tt.active)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (keyring == nil) != tt.wantNil {
+				t.Fatalf("keyring = %v, want nil %v", keyring, tt.wantNil)
+			}
+			if keyring != nil && keyring.ActiveVersion() != tt.active {
+				t.Errorf("ActiveVersion() = %q, want %q", keyring.ActiveVersion(), tt.active)
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	keyring := mustKeyring(t, "v1:"+testKey(1), "v1")
+
+	for _, plaintext := range []string{"", "hunter2", `{"X-Api-Key":"abc"}`, strings.Repeat("x", 10000), "unicode ✓"} {
+		ciphertext, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if plaintext != "" && strings.Contains(ciphertext, plaintext) {
+			t.Errorf("ciphertext contains the plaintext %q", plaintext)
+		}
+
+		got, err := keyring.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if got != plaintext {
+			t.Errorf("round trip = %q, want %q", got, plaintext)
+		}
+	}
+
+	// Fresh data keys and nonces make every ciphertext unique
+	first, _ := keyring.Encrypt("same")
+	second, _ := keyring.Encrypt("same")
+	if first == second {
+		t.Error("encrypting the same value twice gave identical ciphertexts")
+	}
+}
+
+func TestCiphertextPrefix(t *testing.T) {
+	keyring := mustKeyring(t, "v1:"+testKey(1)+",2024-06:"+testKey(2), "2024-06")
+
+	ciphertext, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(ciphertext, "vigil:enc:2024-06:") {
+		t.Errorf("ciphertext %q lacks the vigil:enc:<version>: prefix", ciphertext)
+	}
+	if !IsEncrypted(ciphertext) {
+		t.Error("IsEncrypted(ciphertext) = false")
+	}
+	if version, ok := KeyVersion(ciphertext); !ok || version != "2024-06" {
+		t.Errorf("KeyVersion = %q, %v; want 2024-06, true", version, ok)
+	}
+
+	for _, value := range []string{"secret", "vigil:encrypted", "VIGIL:ENC:v1:a:b"} {
+		if IsEncrypted(value) {
+			t.Errorf("IsEncrypted(%q) = true", value)
+		}
+		if _, ok := KeyVersion(value); ok {
+			t.Errorf("KeyVersion(%q) reported a version", value)
+		}
+	}
+}
+
+func TestDecryptUnderRetiredVersion(t *testing.T) {
+	old := mustKeyring(t, "v1:"+testKey(1), "v1")
+	ciphertext, err := old.Encrypt("rotate me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// v2 is active but v1 is still present, so old ciphertexts remain readable
+	rotated := mustKeyring(t, "v1:"+testKey(1)+",v2:"+testKey(2), "v2")
+	got, err := rotated.Decrypt(ciphertext)
+	if err != nil || got != "rotate me" {
+		t.Fatalf("Decrypt under retired version = %q, %v", got, err)
+	}
+
+	reencrypted, err := rotated.Encrypt(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := KeyVersion(reencrypted); version != "v2" {
+		t.Errorf("new ciphertext uses version %q, want v2", version)
+	}
+
+	// Once v1 is removed its ciphertexts can no longer be read
+	removed := mustKeyring(t, "v2:"+testKey(2), "v2")
+	if _, err := removed.Decrypt(ciphertext); err == nil || !strings.Contains(err.Error(), "unknown key version") {
+		t.Errorf("Decrypt after removing v1: error = %v", err)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	keyring := mustKeyring(t, "v1:"+testKey(1), "v1")
+	ciphertext, err := keyring.Encrypt("do not touch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(ciphertext, prefix), ":")
+
+	flip := func(encoded string) string {
+		raw, _ := base64.StdEncoding.DecodeString(encoded)
+		raw[len(raw)-1] ^= 0x01
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "flipped ciphertext bit", value: prefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2])},
+		{name: "flipped wrapped key bit", value: prefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2]},
+		{name: "truncated ciphertext", value: prefix + parts[0] + ":" + parts[1] + ":" + base64.StdEncoding.EncodeToString([]byte("x"))},
+		{name: "missing part", value: prefix + parts[0] + ":" + parts[1]},
+		{name: "bad base64", value: prefix + parts[0] + ":" + parts[1] + ":%%%"},
+		{name: "wrong master key", value: func() string {
+			other := mustKeyring(t, "v1:"+testKey(9), "v1")
+			value, _ := other.Encrypt("do not touch")
+			return value
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := keyring.Decrypt(tt.value); err == nil {
+				t.Errorf("Decrypt accepted a tampered value and returned %q", got)
+			}
+		})
+	}
+}
+
+func TestDecryptPassesCleartextThrough(t *testing.T) {
+	keyring := mustKeyring(t, "v1:"+testKey(1), "v1")
+
+	for _, value := range []string{"", "legacy-password", `{"url":"https://example.com"}`} {
+		got, err := keyring.Decrypt(value)
+		if err != nil || got != value {
+			t.Errorf("Decrypt(%q) = %q, %v; want the value unchanged", value, got, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// alertRuleRequest is the body accepted when creating or updating an alert rule
+type alertRuleRequest struct {
+	OrganizationID uint   `json:"organization_id"` // only read on create
+	MonitorID      *uint  `json:"monitor_id"`
+	Tags           string `json:"tags"`
+	Name           string `json:"name" validate:"required"`
+	Field          string `json:"field" validate:"required,oneof=response_time status_code status error_message"`
+	Aggregate      string `json:"aggregate" validate:"omitempty,oneof=p50 p90 p95 p99 avg min max"`
+	Operator       string `json:"operator" validate:"required,oneof=gt gte lt lte eq ne contains"`
+	Value          string `json:"value" validate:"required"`
+	WindowMinutes  int    `json:"window_minutes"`
+	WindowChecks   int    `json:"window_checks"`
+	MinMatches     int    `json:"min_matches"` // defaults to 1
+	Severity       string `json:"severity" validate:"omitempty,oneof=low medium high critical"`
+	IsActive       *bool  `json:"is_active"` // defaults to active
+}
+
+// GetAlertRules returns the alert rules of the current user's organizations, optionally
+// only those for one monitor
+func GetAlertRules(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		query := db.Joins("JOIN organizations ON alert_rules.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID)
+		if monitorID := c.Query("monitor_id"); monitorID != "" {
+			id, err := strconv.ParseUint(monitorID, 10, 32)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid monitor ID",
+				})
+			}
+			query = query.Where("alert_rules.monitor_id = ?", id)
+		}
+
+		var rules []database.AlertRule
+		if err := query.Order("alert_rules.id").Find(&rules).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch alert rules",
+			})
+		}
+
+		return c.JSON(rules)
+	}
+}
+
+// CreateAlertRule creates an alert rule for a monitor or a set of tags
+func CreateAlertRule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req alertRuleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		// Verify user owns the organization
+		var organization database.Organization
+		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Organization not found",
+			})
+		}
+
+		rule := database.AlertRule{OrganizationID: organization.ID, IsActive: true}
+		if status, message := applyAlertRule(db, &rule, &req); status != 0 {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		if err := db.Create(&rule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create alert rule",
+			})
+		}
+
+		// Create skips false in favour of the column default, so store an inactive rule explicitly
+		if !rule.IsActive {
+			db.Model(&rule).Update("is_active", false)
+		}
+
+		return c.Status(201).JSON(rule)
+	}
+}
+
+// GetAlertRule returns an alert rule
+func GetAlertRule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		ruleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rule ID",
+			})
+		}
+
+		var rule database.AlertRule
+		if err := db.Joins("JOIN organizations ON alert_rules.organization_id = organizations.id").
+			Where("alert_rules.id = ? AND organizations.owner_id = ?", ruleID, userID).
+			First(&rule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert rule not found",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"rule":      rule,
+			"condition": monitoring.DescribeAlertRule(&rule),
+		})
+	}
+}
+
+// UpdateAlertRule updates an alert rule. Its open alerts are resolved so the new condition
+// is evaluated afresh on the next check.
+func UpdateAlertRule(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		ruleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rule ID",
+			})
+		}
+
+		var req alertRuleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var rule database.AlertRule
+		if err := db.Joins("JOIN organizations ON alert_rules.organization_id = organizations.id").
+			Where("alert_rules.id = ? AND organizations.owner_id = ?", ruleID, userID).
+			First(&rule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert rule not found",
+			})
+		}
+
+		if status, message := applyAlertRule(db, &rule, &req); status != 0 {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		if err := db.Save(&rule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update alert rule",
+			})
+		}
+
+		monitorService.ResolveAlertRuleAlerts(rule.ID)
+
+		return c.JSON(rule)
+	}
+}
+
+// DeleteAlertRule deletes an alert rule, resolving its open alerts. Past alerts are kept.
+func DeleteAlertRule(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		ruleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rule ID",
+			})
+		}
+
+		var rule database.AlertRule
+		if err := db.Joins("JOIN organizations ON alert_rules.organization_id = organizations.id").
+			Where("alert_rules.id = ? AND organizations.owner_id = ?", ruleID, userID).
+			First(&rule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert rule not found",
+			})
+		}
+
+		monitorService.ResolveAlertRuleAlerts(rule.ID)
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&database.Alert{}).Where("alert_rule_id = ?", rule.ID).Update("alert_rule_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&rule).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete alert rule",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// applyAlertRule copies a request onto a rule, checking that its monitor belongs to the
+// rule's organization. It returns a status and message when the request is invalid.
+func applyAlertRule(db *database.DB, rule *database.AlertRule, req *alertRuleRequest) (int, string) {
+	rule.MonitorID = req.MonitorID
+	rule.Tags = strings.Join(monitoring.ParseTags(req.Tags), ",")
+	rule.Name = req.Name
+	rule.Field = req.Field
+	rule.Aggregate = req.Aggregate
+	rule.Operator = req.Operator
+	rule.Value = strings.TrimSpace(req.Value)
+	rule.WindowMinutes = req.WindowMinutes
+	rule.WindowChecks = req.WindowChecks
+	rule.MinMatches = req.MinMatches
+	rule.Severity = req.Severity
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+
+	if rule.MinMatches == 0 {
+		rule.MinMatches = 1
+	}
+	if rule.Severity == "" {
+		rule.Severity = "medium"
+	}
+
+	if rule.MonitorID != nil {
+		var count int64
+		db.Model(&database.Monitor{}).Where("id = ? AND organization_id = ?", *rule.MonitorID, rule.OrganizationID).Count(&count)
+		if count == 0 {
+			return 400, "Monitor not found in the rule's organization"
+		}
+	}
+
+	if err := monitoring.ValidateAlertRule(rule); err != nil {
+		return 400, err.Error()
+	}
+
+	return 0, ""
+}
@@ -2,21 +2,30 @@ package handlers
 
 import (
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 
 	"vigil/internal/database"
 )
 
+// alertsForOwner scopes an alerts query to the monitors and webhooks of the user's organizations
+func alertsForOwner(db *database.DB, userID uint) *gorm.DB {
+	return db.Joins("LEFT JOIN monitors ON alerts.monitor_id = monitors.id").
+		Joins("LEFT JOIN webhooks ON alerts.webhook_id = webhooks.id").
+		Joins("JOIN organizations ON organizations.id = COALESCE(monitors.organization_id, webhooks.organization_id)").
+		Where("organizations.owner_id = ?", userID)
+}
+
 // GetAlerts returns all alerts for the current user's organizations
 func GetAlerts(db *database.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID := c.Locals("user_id").(uint)
 
 		var alerts []database.Alert
-		if err := db.Joins("JOIN monitors ON alerts.monitor_id = monitors.id").
-			Joins("JOIN organizations ON monitors.organization_id = organizations.id").
-			Where("organizations.owner_id = ?", userID).
+		if err := alertsForOwner(db, userID).
 			Order("alerts.created_at DESC").
 			Limit(100).
 			Find(&alerts).Error; err != nil {
@@ -41,9 +50,8 @@ func GetAlert(db *database.DB) fiber.Handler {
 		}
 
 		var alert database.Alert
-		if err := db.Joins("JOIN monitors ON alerts.monitor_id = monitors.id").
-			Joins("JOIN organizations ON monitors.organization_id = organizations.id").
-			Where("alerts.id = ? AND organizations.owner_id = ?", alertID, userID).
+		if err := alertsForOwner(db, userID).
+			Where("alerts.id = ?", alertID).
 			First(&alert).Error; err != nil {
 			return c.Status(404).JSON(fiber.Map{
 				"error": "Alert not found",
@@ -66,9 +74,8 @@ func ResolveAlert(db *database.DB) fiber.Handler {
 		}
 
 		var alert database.Alert
-		if err := db.Joins("JOIN monitors ON alerts.monitor_id = monitors.id").
-			Joins("JOIN organizations ON monitors.organization_id = organizations.id").
-			Where("alerts.id = ? AND organizations.owner_id = ?", alertID, userID).
+		if err := alertsForOwner(db, userID).
+			Where("alerts.id = ?", alertID).
 			First(&alert).Error; err != nil {
 			return c.Status(404).JSON(fiber.Map{
 				"error": "Alert not found",
@@ -82,6 +89,249 @@ func ResolveAlert(db *database.DB) fiber.Handler {
 			})
 		}
 
+		recordAlertEvent(db, database.AlertEvent{
+			AlertID: alert.ID,
+			Type:    "resolved",
+			ActorID: &userID,
+		})
+
+		return c.JSON(alert)
+	}
+}
+
+// AcknowledgeAlert acknowledges an open alert, which stops re-notifications
+func AcknowledgeAlert(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		alertID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid alert ID",
+			})
+		}
+
+		var alert database.Alert
+		if err := alertsForOwner(db, userID).
+			Where("alerts.id = ?", alertID).
+			First(&alert).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert not found",
+			})
+		}
+
+		if alert.ResolvedAt != nil {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Alert is already resolved",
+			})
+		}
+
+		// Only the first acknowledgement counts
+		now := time.Now()
+		result := db.Model(&database.Alert{}).
+			Where("id = ? AND acknowledged_at IS NULL", alert.ID).
+			Updates(map[string]interface{}{
+				"acknowledged_at":    now,
+				"acknowledged_by_id": userID,
+			})
+		if result.Error != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to acknowledge alert",
+			})
+		}
+
+		if result.RowsAffected == 0 {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Alert is already acknowledged",
+			})
+		}
+
+		recordAlertEvent(db, database.AlertEvent{
+			AlertID: alert.ID,
+			Type:    "acknowledged",
+			ActorID: &userID,
+		})
+
+		alert.AcknowledgedAt = &now
+		alert.AcknowledgedByID = &userID
+		return c.JSON(alert)
+	}
+}
+
+// AssignAlert assigns an alert to a member of its organization, or unassigns it when
+// user_id is null
+func AssignAlert(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		alertID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid alert ID",
+			})
+		}
+
+		var req struct {
+			UserID *uint `json:"user_id"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var alert database.Alert
+		if err := alertsForOwner(db, userID).
+			Where("alerts.id = ?", alertID).
+			First(&alert).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert not found",
+			})
+		}
+
+		event := database.AlertEvent{
+			AlertID: alert.ID,
+			Type:    "unassigned",
+			ActorID: &userID,
+		}
+
+		if req.UserID != nil {
+			var organizationIDs []uint
+			if err := alertsForOwner(db, userID).
+				Model(&database.Alert{}).
+				Where("alerts.id = ?", alert.ID).
+				Pluck("organizations.id", &organizationIDs).Error; err != nil || len(organizationIDs) == 0 {
+				return c.Status(500).JSON(fiber.Map{
+					"error": "Failed to load alert organization",
+				})
+			}
+
+			if !isOrganizationMember(db, organizationIDs[0], *req.UserID) {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Assignee must be a member of the alert's organization",
+				})
+			}
+
+			event.Type = "assigned"
+			event.AssigneeID = req.UserID
+		}
+
+		if err := db.Model(&alert).Update("assigned_to_id", req.UserID).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to assign alert",
+			})
+		}
+
+		recordAlertEvent(db, event)
+
+		alert.AssignedToID = req.UserID
 		return c.JSON(alert)
 	}
 }
+
+// AddAlertNote adds a free-text note to an alert's timeline
+func AddAlertNote(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		alertID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid alert ID",
+			})
+		}
+
+		var req struct {
+			Note string `json:"note" validate:"required"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if strings.TrimSpace(req.Note) == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "note is required",
+			})
+		}
+
+		var alert database.Alert
+		if err := alertsForOwner(db, userID).
+			Where("alerts.id = ?", alertID).
+			First(&alert).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert not found",
+			})
+		}
+
+		event := database.AlertEvent{
+			AlertID:   alert.ID,
+			Type:      "note",
+			ActorID:   &userID,
+			Message:   req.Note,
+			CreatedAt: time.Now(),
+		}
+
+		if err := db.Create(&event).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to add note",
+			})
+		}
+
+		return c.Status(201).JSON(event)
+	}
+}
+
+// GetAlertTimeline returns the events of an alert, oldest first
+func GetAlertTimeline(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		alertID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid alert ID",
+			})
+		}
+
+		var alert database.Alert
+		if err := alertsForOwner(db, userID).
+			Where("alerts.id = ?", alertID).
+			First(&alert).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Alert not found",
+			})
+		}
+
+		var events []database.AlertEvent
+		if err := db.Preload("Actor").
+			Preload("Assignee").
+			Where("alert_id = ?", alert.ID).
+			Order("created_at, id").
+			Find(&events).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch alert timeline",
+			})
+		}
+
+		return c.JSON(events)
+	}
+}
+
+// recordAlertEvent adds an event to an alert's timeline. Failures are not surfaced since
+// the action itself already succeeded.
+func recordAlertEvent(db *database.DB, event database.AlertEvent) {
+	event.CreatedAt = time.Now()
+	db.Create(&event)
+}
+
+// isOrganizationMember reports whether a user owns or belongs to an organization
+func isOrganizationMember(db *database.DB, organizationID, userID uint) bool {
+	var count int64
+	db.Model(&database.Organization{}).Where("id = ? AND owner_id = ?", organizationID, userID).Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	db.Model(&database.OrganizationMember{}).Where("organization_id = ? AND user_id = ?", organizationID, userID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// GetContactMethods returns the current user's contact methods
+func GetContactMethods(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var methods []database.ContactMethod
+		if err := db.Where("user_id = ?", userID).Order("id").Find(&methods).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch contact methods",
+			})
+		}
+
+		return c.JSON(methods)
+	}
+}
+
+// CreateContactMethod adds a way to page the current user
+func CreateContactMethod(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req struct {
+			Type    string `json:"type" validate:"required,oneof=email slack discord webhook"`
+			Address string `json:"address" validate:"required"`
+			Label   string `json:"label"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		method := database.ContactMethod{
+			UserID:  userID,
+			Type:    req.Type,
+			Address: req.Address,
+			Label:   req.Label,
+		}
+
+		if err := monitoring.ValidateContactMethod(&method); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Create(&method).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create contact method",
+			})
+		}
+
+		return c.Status(201).JSON(method)
+	}
+}
+
+// UpdateContactMethod updates one of the current user's contact methods
+func UpdateContactMethod(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		methodID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid contact method ID",
+			})
+		}
+
+		var req struct {
+			Type    string `json:"type" validate:"required,oneof=email slack discord webhook"`
+			Address string `json:"address" validate:"required"`
+			Label   string `json:"label"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var method database.ContactMethod
+		if err := db.Where("id = ? AND user_id = ?", methodID, userID).First(&method).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Contact method not found",
+			})
+		}
+
+		method.Type = req.Type
+		method.Address = req.Address
+		method.Label = req.Label
+
+		if err := monitoring.ValidateContactMethod(&method); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Save(&method).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update contact method",
+			})
+		}
+
+		return c.JSON(method)
+	}
+}
+
+// DeleteContactMethod removes one of the current user's contact methods
+func DeleteContactMethod(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		methodID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid contact method ID",
+			})
+		}
+
+		result := db.Where("id = ? AND user_id = ?", methodID, userID).Delete(&database.ContactMethod{})
+		if result.Error != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete contact method",
+			})
+		}
+		if result.RowsAffected == 0 {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Contact method not found",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
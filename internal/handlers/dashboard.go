@@ -35,9 +35,8 @@ func GetDashboardStats(db *database.DB) fiber.Handler {
 
 		// Get active alerts
 		var activeAlerts int64
-		if err := db.Joins("JOIN monitors ON alerts.monitor_id = monitors.id").
-			Joins("JOIN organizations ON monitors.organization_id = organizations.id").
-			Where("organizations.owner_id = ? AND alerts.resolved_at IS NULL", userID).
+		if err := alertsForOwner(db, userID).
+			Where("alerts.resolved_at IS NULL").
 			Model(&database.Alert{}).Count(&activeAlerts).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": "Failed to get active alert count",
@@ -53,11 +52,37 @@ func GetDashboardStats(db *database.DB) fiber.Handler {
 			})
 		}
 
+		// Get dead-letter queue depth per webhook
+		var deadLetters []struct {
+			WebhookID   uint   `json:"webhook_id"`
+			WebhookName string `json:"webhook_name"`
+			Depth       int64  `json:"depth"`
+		}
+		if err := db.Table("webhook_deliveries").
+			Select("webhooks.id AS webhook_id, webhooks.name AS webhook_name, COUNT(*) AS depth").
+			Joins("JOIN webhooks ON webhook_deliveries.webhook_id = webhooks.id").
+			Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("organizations.owner_id = ? AND webhook_deliveries.status = ?", userID, "dead_letter").
+			Group("webhooks.id, webhooks.name").
+			Order("depth DESC").
+			Scan(&deadLetters).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to get dead-letter queue depth",
+			})
+		}
+
+		var deadLetterTotal int64
+		for _, queue := range deadLetters {
+			deadLetterTotal += queue.Depth
+		}
+
 		return c.JSON(fiber.Map{
 			"total_monitors":      totalMonitors,
 			"active_monitors":     activeMonitors,
 			"active_alerts":       activeAlerts,
 			"total_organizations": totalOrganizations,
+			"dead_letter_total":   deadLetterTotal,
+			"dead_letter_queues":  deadLetters,
 		})
 	}
 }
@@ -99,31 +124,38 @@ func GetUptimeStats(db *database.DB) fiber.Handler {
 
 			startDate := time.Now().AddDate(0, 0, -daysInt)
 
+			// Planned downtime doesn't count against uptime
 			if err := db.Model(&database.MonitorCheck{}).
-				Where("monitor_id = ? AND checked_at >= ?", monitor.ID, startDate).
+				Where("monitor_id = ? AND checked_at >= ? AND in_maintenance = ?", monitor.ID, startDate, false).
 				Count(&totalChecks).Error; err != nil {
 				continue
 			}
 
 			if err := db.Model(&database.MonitorCheck{}).
-				Where("monitor_id = ? AND status = 'up' AND checked_at >= ?", monitor.ID, startDate).
+				Where("monitor_id = ? AND status = 'up' AND checked_at >= ? AND in_maintenance = ?", monitor.ID, startDate, false).
 				Count(&successfulChecks).Error; err != nil {
 				continue
 			}
 
+			var maintenanceChecks int64
+			db.Model(&database.MonitorCheck{}).
+				Where("monitor_id = ? AND checked_at >= ? AND in_maintenance = ?", monitor.ID, startDate, true).
+				Count(&maintenanceChecks)
+
 			var uptimePercentage float64
 			if totalChecks > 0 {
 				uptimePercentage = float64(successfulChecks) / float64(totalChecks) * 100
 			}
 
 			uptimeStats = append(uptimeStats, fiber.Map{
-				"monitor_id":        monitor.ID,
-				"monitor_name":      monitor.Name,
-				"monitor_type":      monitor.Type,
-				"uptime_percentage": uptimePercentage,
-				"total_checks":      totalChecks,
-				"successful_checks": successfulChecks,
-				"period_days":       daysInt,
+				"monitor_id":         monitor.ID,
+				"monitor_name":       monitor.Name,
+				"monitor_type":       monitor.Type,
+				"uptime_percentage":  uptimePercentage,
+				"total_checks":       totalChecks,
+				"successful_checks":  successfulChecks,
+				"maintenance_checks": maintenanceChecks,
+				"period_days":        daysInt,
 			})
 		}
 
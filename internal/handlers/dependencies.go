@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// GetDependencyTree returns the current user's monitors arranged by their dependencies, each
+// with its latest status
+func GetDependencyTree(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var monitors []database.Monitor
+		if err := db.Joins("JOIN organizations ON monitors.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID).
+			Order("monitors.name").
+			Find(&monitors).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch monitors",
+			})
+		}
+
+		tree, err := monitorService.DependencyTree(monitors)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to build dependency tree",
+			})
+		}
+
+		return c.JSON(tree)
+	}
+}
+
+// GetMonitorParents returns the monitors a monitor depends on
+func GetMonitorParents(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		monitorID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid monitor ID",
+			})
+		}
+
+		var monitor database.Monitor
+		if err := db.Joins("JOIN organizations ON monitors.organization_id = organizations.id").
+			Where("monitors.id = ? AND organizations.owner_id = ?", monitorID, userID).
+			First(&monitor).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Monitor not found",
+			})
+		}
+
+		var parents []database.Monitor
+		if err := db.Joins("JOIN monitor_dependencies ON monitor_dependencies.parent_id = monitors.id").
+			Where("monitor_dependencies.monitor_id = ?", monitor.ID).
+			Find(&parents).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch parent monitors",
+			})
+		}
+
+		return c.JSON(parents)
+	}
+}
+
+// SetMonitorParents replaces the monitors a monitor depends on
+func SetMonitorParents(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		monitorID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid monitor ID",
+			})
+		}
+
+		var req struct {
+			ParentIDs []uint `json:"parent_ids"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var monitor database.Monitor
+		if err := db.Joins("JOIN organizations ON monitors.organization_id = organizations.id").
+			Where("monitors.id = ? AND organizations.owner_id = ?", monitorID, userID).
+			First(&monitor).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Monitor not found",
+			})
+		}
+
+		// Parents must be other monitors in the same organization
+		parentIDs := make([]uint, 0, len(req.ParentIDs))
+		seen := make(map[uint]bool)
+		for _, id := range req.ParentIDs {
+			if !seen[id] {
+				seen[id] = true
+				parentIDs = append(parentIDs, id)
+			}
+		}
+
+		parents := []database.Monitor{}
+		if len(parentIDs) > 0 {
+			if err := db.Where("id IN ? AND organization_id = ?", parentIDs, monitor.OrganizationID).
+				Find(&parents).Error; err != nil {
+				return c.Status(500).JSON(fiber.Map{
+					"error": "Failed to load parent monitors",
+				})
+			}
+			if len(parents) != len(parentIDs) {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Some parent monitors were not found in the monitor's organization",
+				})
+			}
+		}
+
+		var dependencies []database.MonitorDependency
+		if err := db.Joins("JOIN monitors ON monitor_dependencies.monitor_id = monitors.id").
+			Where("monitors.organization_id = ?", monitor.OrganizationID).
+			Find(&dependencies).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to load monitor dependencies",
+			})
+		}
+
+		if err := monitoring.ValidateMonitorDependencies(dependencies, monitor.ID, parentIDs); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("monitor_id = ?", monitor.ID).Delete(&database.MonitorDependency{}).Error; err != nil {
+				return err
+			}
+			for _, parentID := range parentIDs {
+				if err := tx.Create(&database.MonitorDependency{MonitorID: monitor.ID, ParentID: parentID}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update monitor dependencies",
+			})
+		}
+
+		return c.JSON(parents)
+	}
+}
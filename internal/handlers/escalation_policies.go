@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// escalationLevelRequest is one level of an escalation policy as sent by clients
+type escalationLevelRequest struct {
+	DelayMinutes int `json:"delay_minutes"`
+	Targets      []struct {
+		Type                  string `json:"type" validate:"required,oneof=channel user schedule"`
+		NotificationChannelID *uint  `json:"notification_channel_id"`
+		UserID                *uint  `json:"user_id"`
+		ScheduleID            *uint  `json:"schedule_id"`
+	} `json:"targets"`
+}
+
+// GetEscalationPolicies returns all escalation policies for the current user's organizations
+func GetEscalationPolicies(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var policies []database.EscalationPolicy
+		if err := db.Joins("JOIN organizations ON escalation_policies.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID).
+			Preload("Levels", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+			Preload("Levels.Targets").
+			Find(&policies).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch escalation policies",
+			})
+		}
+
+		return c.JSON(policies)
+	}
+}
+
+// CreateEscalationPolicy creates an escalation policy with its levels
+func CreateEscalationPolicy(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req struct {
+			OrganizationID uint                     `json:"organization_id" validate:"required"`
+			Name           string                   `json:"name" validate:"required"`
+			Description    string                   `json:"description"`
+			Levels         []escalationLevelRequest `json:"levels" validate:"required"`
+
+			ReminderIntervalMinutes int `json:"reminder_interval_minutes"` // 0 turns reminders off
+			MaxReminders            int `json:"max_reminders"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		// Verify user owns the organization
+		var organization database.Organization
+		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Organization not found",
+			})
+		}
+
+		levels, err := buildEscalationLevels(db, organization.ID, req.Levels)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := monitoring.ValidateReminders(req.ReminderIntervalMinutes, req.MaxReminders); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		policy := database.EscalationPolicy{
+			OrganizationID: organization.ID,
+			Name:           req.Name,
+			Description:    req.Description,
+			Levels:         levels,
+
+			ReminderIntervalMinutes: req.ReminderIntervalMinutes,
+			MaxReminders:            req.MaxReminders,
+		}
+
+		if err := db.Create(&policy).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create escalation policy",
+			})
+		}
+
+		return c.Status(201).JSON(policy)
+	}
+}
+
+// GetEscalationPolicy returns a specific escalation policy
+func GetEscalationPolicy(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		policyID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid escalation policy ID",
+			})
+		}
+
+		var policy database.EscalationPolicy
+		if err := db.Joins("JOIN organizations ON escalation_policies.organization_id = organizations.id").
+			Where("escalation_policies.id = ? AND organizations.owner_id = ?", policyID, userID).
+			Preload("Levels", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+			Preload("Levels.Targets").
+			First(&policy).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Escalation policy not found",
+			})
+		}
+
+		return c.JSON(policy)
+	}
+}
+
+// UpdateEscalationPolicy updates an escalation policy, replacing its levels. Alerts already
+// escalating continue from the position they reached.
+func UpdateEscalationPolicy(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		policyID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid escalation policy ID",
+			})
+		}
+
+		var req struct {
+			Name        string                   `json:"name" validate:"required"`
+			Description string                   `json:"description"`
+			Levels      []escalationLevelRequest `json:"levels" validate:"required"`
+
+			ReminderIntervalMinutes int `json:"reminder_interval_minutes"` // 0 turns reminders off
+			MaxReminders            int `json:"max_reminders"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var policy database.EscalationPolicy
+		if err := db.Joins("JOIN organizations ON escalation_policies.organization_id = organizations.id").
+			Where("escalation_policies.id = ? AND organizations.owner_id = ?", policyID, userID).
+			First(&policy).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Escalation policy not found",
+			})
+		}
+
+		levels, err := buildEscalationLevels(db, policy.OrganizationID, req.Levels)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := monitoring.ValidateReminders(req.ReminderIntervalMinutes, req.MaxReminders); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		policy.Name = req.Name
+		policy.Description = req.Description
+		policy.ReminderIntervalMinutes = req.ReminderIntervalMinutes
+		policy.MaxReminders = req.MaxReminders
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := deleteEscalationLevels(tx, policy.ID); err != nil {
+				return err
+			}
+			for i := range levels {
+				levels[i].EscalationPolicyID = policy.ID
+			}
+			if err := tx.Create(&levels).Error; err != nil {
+				return err
+			}
+			return tx.Omit("Levels").Save(&policy).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update escalation policy",
+			})
+		}
+
+		policy.Levels = levels
+		return c.JSON(policy)
+	}
+}
+
+// DeleteEscalationPolicy deletes an escalation policy. Monitors using it fall back to
+// notifying every channel, and alerts escalating under it stop escalating.
+func DeleteEscalationPolicy(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		policyID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid escalation policy ID",
+			})
+		}
+
+		var policy database.EscalationPolicy
+		if err := db.Joins("JOIN organizations ON escalation_policies.organization_id = organizations.id").
+			Where("escalation_policies.id = ? AND organizations.owner_id = ?", policyID, userID).
+			First(&policy).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Escalation policy not found",
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&database.Monitor{}).Where("escalation_policy_id = ?", policy.ID).
+				Update("escalation_policy_id", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&database.Alert{}).Where("escalation_policy_id = ?", policy.ID).
+				Updates(map[string]interface{}{"escalation_policy_id": nil, "next_escalation_at": nil}).Error; err != nil {
+				return err
+			}
+			if err := deleteEscalationLevels(tx, policy.ID); err != nil {
+				return err
+			}
+			return tx.Delete(&policy).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete escalation policy",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// buildEscalationLevels validates requested levels and checks that every target belongs
+// to the organization
+func buildEscalationLevels(db *database.DB, organizationID uint, requested []escalationLevelRequest) ([]database.EscalationLevel, error) {
+	levels := make([]database.EscalationLevel, 0, len(requested))
+	for i, req := range requested {
+		level := database.EscalationLevel{
+			Position:     i + 1,
+			DelayMinutes: req.DelayMinutes,
+		}
+		for _, target := range req.Targets {
+			level.Targets = append(level.Targets, database.EscalationTarget{
+				Type:                  target.Type,
+				NotificationChannelID: target.NotificationChannelID,
+				UserID:                target.UserID,
+				ScheduleID:            target.ScheduleID,
+			})
+		}
+		levels = append(levels, level)
+	}
+
+	if err := monitoring.ValidateEscalationLevels(levels); err != nil {
+		return nil, err
+	}
+
+	for _, level := range levels {
+		for _, target := range level.Targets {
+			switch target.Type {
+			case "channel":
+				var count int64
+				db.Model(&database.NotificationChannel{}).
+					Where("id = ? AND organization_id = ?", *target.NotificationChannelID, organizationID).
+					Count(&count)
+				if count == 0 {
+					return nil, fmt.Errorf("level %d: notification channel %d not found", level.Position, *target.NotificationChannelID)
+				}
+			case "user":
+				if !isOrganizationMember(db, organizationID, *target.UserID) {
+					return nil, fmt.Errorf("level %d: user %d is not a member of the organization", level.Position, *target.UserID)
+				}
+			case "schedule":
+				var count int64
+				db.Model(&database.OnCallSchedule{}).
+					Where("id = ? AND organization_id = ?", *target.ScheduleID, organizationID).
+					Count(&count)
+				if count == 0 {
+					return nil, fmt.Errorf("level %d: on-call schedule %d not found", level.Position, *target.ScheduleID)
+				}
+			}
+		}
+	}
+
+	return levels, nil
+}
+
+// deleteEscalationLevels removes a policy's levels and their targets
+func deleteEscalationLevels(tx *gorm.DB, policyID uint) error {
+	if err := tx.Where("escalation_level_id IN (?)",
+		tx.Model(&database.EscalationLevel{}).Select("id").Where("escalation_policy_id = ?", policyID)).
+		Delete(&database.EscalationTarget{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("escalation_policy_id = ?", policyID).Delete(&database.EscalationLevel{}).Error
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// GetIncidents returns incidents for the current user's organizations, optionally filtered by ?status=
+func GetIncidents(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		query := db.Joins("JOIN organizations ON incidents.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID)
+
+		if status := c.Query("status"); status != "" {
+			if err := monitoring.ValidateIncidentStatus(status); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			query = query.Where("incidents.status = ?", status)
+		}
+
+		var incidents []database.Incident
+		if err := query.Order("incidents.started_at DESC").Find(&incidents).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch incidents",
+			})
+		}
+
+		return c.JSON(incidents)
+	}
+}
+
+// GetIncident returns an incident with its alerts and updates
+func GetIncident(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		incidentID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid incident ID",
+			})
+		}
+
+		var incident database.Incident
+		if err := db.Joins("JOIN organizations ON incidents.organization_id = organizations.id").
+			Where("incidents.id = ? AND organizations.owner_id = ?", incidentID, userID).
+			Preload("Alerts", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+			Preload("Alerts.Monitor").
+			Preload("Alerts.Webhook").
+			Preload("Updates", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+			Preload("Updates.Author").
+			First(&incident).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Incident not found",
+			})
+		}
+
+		return c.JSON(incident)
+	}
+}
+
+// UpdateIncident renames an incident
+func UpdateIncident(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		incidentID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid incident ID",
+			})
+		}
+
+		var req struct {
+			Title string `json:"title" validate:"required"`
+		}
+
+		if err := c.BodyParser(&req); err != nil || req.Title == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var incident database.Incident
+		if err := db.Joins("JOIN organizations ON incidents.organization_id = organizations.id").
+			Where("incidents.id = ? AND organizations.owner_id = ?", incidentID, userID).
+			First(&incident).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Incident not found",
+			})
+		}
+
+		if err := db.Model(&incident).Update("title", req.Title).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update incident",
+			})
+		}
+
+		return c.JSON(incident)
+	}
+}
+
+// AddIncidentUpdate posts an update on an incident, moving it to a new status when one is
+// given. Incident status is independent of the status of its alerts.
+func AddIncidentUpdate(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		incidentID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid incident ID",
+			})
+		}
+
+		var req struct {
+			Status  string `json:"status" validate:"omitempty,oneof=investigating identified monitoring resolved"`
+			Message string `json:"message"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if req.Status == "" && req.Message == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "A status or message is required",
+			})
+		}
+
+		if req.Status != "" {
+			if err := monitoring.ValidateIncidentStatus(req.Status); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+
+		var incident database.Incident
+		if err := db.Joins("JOIN organizations ON incidents.organization_id = organizations.id").
+			Where("incidents.id = ? AND organizations.owner_id = ?", incidentID, userID).
+			First(&incident).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Incident not found",
+			})
+		}
+
+		if req.Status == "" {
+			req.Status = incident.Status
+		}
+
+		now := time.Now()
+		update := database.IncidentUpdate{
+			IncidentID: incident.ID,
+			Status:     req.Status,
+			Message:    req.Message,
+			AuthorID:   &userID,
+			CreatedAt:  now,
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if req.Status != incident.Status {
+				changes := map[string]interface{}{"status": req.Status, "resolved_at": nil}
+				if req.Status == "resolved" {
+					changes["resolved_at"] = now
+				}
+				if err := tx.Model(&incident).Updates(changes).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Create(&update).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update incident",
+			})
+		}
+
+		return c.Status(201).JSON(update)
+	}
+}
+
+// GetIncidentRules returns the incident grouping rules for the current user's organizations
+func GetIncidentRules(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var rules []database.IncidentRule
+		if err := db.Joins("JOIN organizations ON incident_rules.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID).
+			Order("incident_rules.id").
+			Find(&rules).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch incident rules",
+			})
+		}
+
+		return c.JSON(rules)
+	}
+}
+
+// CreateIncidentRule creates an incident grouping rule
+func CreateIncidentRule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req struct {
+			OrganizationID uint   `json:"organization_id" validate:"required"`
+			Name           string `json:"name" validate:"required"`
+			GroupBy        string `json:"group_by" validate:"required,oneof=host group time"`
+			WindowMinutes  int    `json:"window_minutes"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		// Verify user owns the organization
+		var organization database.Organization
+		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Organization not found",
+			})
+		}
+
+		if req.WindowMinutes == 0 {
+			req.WindowMinutes = 10
+		}
+
+		rule := database.IncidentRule{
+			OrganizationID: organization.ID,
+			Name:           req.Name,
+			GroupBy:        req.GroupBy,
+			WindowMinutes:  req.WindowMinutes,
+			IsActive:       true,
+		}
+
+		if err := monitoring.ValidateIncidentRule(&rule); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Create(&rule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create incident rule",
+			})
+		}
+
+		return c.Status(201).JSON(rule)
+	}
+}
+
+// UpdateIncidentRule updates an incident grouping rule
+func UpdateIncidentRule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		ruleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rule ID",
+			})
+		}
+
+		var req struct {
+			Name          string `json:"name" validate:"required"`
+			GroupBy       string `json:"group_by" validate:"required,oneof=host group time"`
+			WindowMinutes int    `json:"window_minutes"`
+			IsActive      bool   `json:"is_active"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var rule database.IncidentRule
+		if err := db.Joins("JOIN organizations ON incident_rules.organization_id = organizations.id").
+			Where("incident_rules.id = ? AND organizations.owner_id = ?", ruleID, userID).
+			First(&rule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Incident rule not found",
+			})
+		}
+
+		rule.Name = req.Name
+		rule.GroupBy = req.GroupBy
+		rule.WindowMinutes = req.WindowMinutes
+		rule.IsActive = req.IsActive
+
+		if err := monitoring.ValidateIncidentRule(&rule); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Save(&rule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update incident rule",
+			})
+		}
+
+		return c.JSON(rule)
+	}
+}
+
+// DeleteIncidentRule deletes an incident grouping rule. Incidents it opened are kept.
+func DeleteIncidentRule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		ruleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rule ID",
+			})
+		}
+
+		var rule database.IncidentRule
+		if err := db.Joins("JOIN organizations ON incident_rules.organization_id = organizations.id").
+			Where("incident_rules.id = ? AND organizations.owner_id = ?", ruleID, userID).
+			First(&rule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Incident rule not found",
+			})
+		}
+
+		if err := db.Delete(&rule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete incident rule",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// maintenanceWindowRequest is the body accepted when creating or updating a maintenance window
+type maintenanceWindowRequest struct {
+	OrganizationID  uint      `json:"organization_id"` // only read on create
+	Name            string    `json:"name" validate:"required"`
+	Description     string    `json:"description"`
+	StartsAt        time.Time `json:"starts_at" validate:"required"`
+	DurationMinutes int       `json:"duration_minutes" validate:"required,min=1"`
+	Timezone        string    `json:"timezone"`
+	RRule           string    `json:"rrule"`
+	Tags            string    `json:"tags"`
+	MonitorIDs      []uint    `json:"monitor_ids"`
+}
+
+// GetMaintenanceWindows returns the maintenance windows of the current user's organizations
+// with their current or next occurrence
+func GetMaintenanceWindows(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var windows []database.MaintenanceWindow
+		if err := db.Joins("JOIN organizations ON maintenance_windows.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID).
+			Preload("Monitors", func(db *gorm.DB) *gorm.DB { return db.Select("monitors.id", "monitors.name") }).
+			Order("maintenance_windows.starts_at").
+			Find(&windows).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch maintenance windows",
+			})
+		}
+
+		now := time.Now()
+		result := make([]fiber.Map, 0, len(windows))
+		for i := range windows {
+			_, active := monitoring.MaintenanceOccurrenceAt(&windows[i], now)
+
+			var next *monitoring.MaintenanceOccurrence
+			if upcoming := monitoring.UpcomingMaintenance(&windows[i], now, 1); len(upcoming) > 0 {
+				next = &upcoming[0]
+			}
+
+			result = append(result, fiber.Map{
+				"window":          windows[i],
+				"active":          active,
+				"next_occurrence": next,
+			})
+		}
+
+		return c.JSON(result)
+	}
+}
+
+// CreateMaintenanceWindow schedules a one-off or recurring maintenance window
+func CreateMaintenanceWindow(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req maintenanceWindowRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		// Verify user owns the organization
+		var organization database.Organization
+		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Organization not found",
+			})
+		}
+
+		window := database.MaintenanceWindow{
+			OrganizationID: organization.ID,
+			CreatedByID:    userID,
+		}
+		if status, message := applyMaintenanceWindow(db, &window, &req); status != 0 {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		if err := db.Create(&window).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create maintenance window",
+			})
+		}
+
+		return c.Status(201).JSON(window)
+	}
+}
+
+// GetMaintenanceWindow returns a maintenance window with its upcoming occurrences
+func GetMaintenanceWindow(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		windowID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid maintenance window ID",
+			})
+		}
+
+		limit, err := strconv.Atoi(c.Query("occurrences", "10"))
+		if err != nil || limit < 1 || limit > 100 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "occurrences must be between 1 and 100",
+			})
+		}
+
+		var window database.MaintenanceWindow
+		if err := db.Joins("JOIN organizations ON maintenance_windows.organization_id = organizations.id").
+			Where("maintenance_windows.id = ? AND organizations.owner_id = ?", windowID, userID).
+			Preload("Monitors", func(db *gorm.DB) *gorm.DB { return db.Select("monitors.id", "monitors.name") }).
+			First(&window).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Maintenance window not found",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"window":      window,
+			"occurrences": monitoring.UpcomingMaintenance(&window, time.Now(), limit),
+		})
+	}
+}
+
+// UpdateMaintenanceWindow updates a maintenance window and the monitors it covers
+func UpdateMaintenanceWindow(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		windowID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid maintenance window ID",
+			})
+		}
+
+		var req maintenanceWindowRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var window database.MaintenanceWindow
+		if err := db.Joins("JOIN organizations ON maintenance_windows.organization_id = organizations.id").
+			Where("maintenance_windows.id = ? AND organizations.owner_id = ?", windowID, userID).
+			First(&window).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Maintenance window not found",
+			})
+		}
+
+		if status, message := applyMaintenanceWindow(db, &window, &req); status != 0 {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&window).Association("Monitors").Replace(window.Monitors); err != nil {
+				return err
+			}
+			return tx.Omit("Monitors").Save(&window).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update maintenance window",
+			})
+		}
+
+		return c.JSON(window)
+	}
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func DeleteMaintenanceWindow(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		windowID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid maintenance window ID",
+			})
+		}
+
+		var window database.MaintenanceWindow
+		if err := db.Joins("JOIN organizations ON maintenance_windows.organization_id = organizations.id").
+			Where("maintenance_windows.id = ? AND organizations.owner_id = ?", windowID, userID).
+			First(&window).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Maintenance window not found",
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&window).Association("Monitors").Clear(); err != nil {
+				return err
+			}
+			return tx.Delete(&window).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete maintenance window",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// applyMaintenanceWindow copies a request onto a window, loading the monitors it names from
+// the window's organization. It returns a status and message when the request is invalid.
+func applyMaintenanceWindow(db *database.DB, window *database.MaintenanceWindow, req *maintenanceWindowRequest) (int, string) {
+	window.Name = req.Name
+	window.Description = req.Description
+	window.StartsAt = req.StartsAt
+	window.DurationMinutes = req.DurationMinutes
+	window.Timezone = req.Timezone
+	window.RRule = strings.TrimSpace(req.RRule)
+	window.Tags = strings.Join(monitoring.ParseTags(req.Tags), ",")
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+	}
+
+	// Repeated IDs would otherwise look like monitors that were not found
+	monitorIDs := make([]uint, 0, len(req.MonitorIDs))
+	seen := make(map[uint]bool)
+	for _, id := range req.MonitorIDs {
+		if !seen[id] {
+			seen[id] = true
+			monitorIDs = append(monitorIDs, id)
+		}
+	}
+
+	window.Monitors = nil
+	if len(monitorIDs) > 0 {
+		if err := db.Where("id IN ? AND organization_id = ?", monitorIDs, window.OrganizationID).
+			Find(&window.Monitors).Error; err != nil {
+			return 500, "Failed to load monitors"
+		}
+		if len(window.Monitors) != len(monitorIDs) {
+			return 400, "Some monitors were not found in the window's organization"
+		}
+	}
+
+	if err := monitoring.ValidateMaintenanceWindow(window); err != nil {
+		return 400, err.Error()
+	}
+
+	return 0, ""
+}
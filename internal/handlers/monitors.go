@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 
 	"vigil/internal/database"
 	"vigil/internal/monitoring"
@@ -37,10 +40,31 @@ func CreateMonitor(db *database.DB, monitorService *monitoring.Service) fiber.Ha
 			Name            string `json:"name" validate:"required"`
 			Type            string `json:"type" validate:"required,oneof=http ssl webhook"`
 			URL             string `json:"url" validate:"required"`
+			Group           string `json:"group"`
+			Tags            string `json:"tags"` // comma separated, used by maintenance windows
 			IntervalSeconds int    `json:"interval_seconds" validate:"required,min=30"`
 			TimeoutSeconds  int    `json:"timeout_seconds" validate:"required,min=5"`
 			ExpectedStatus  int    `json:"expected_status" validate:"required,min=100,max=599"`
 			CustomHeaders   string `json:"custom_headers"`
+			Method          string `json:"method" validate:"omitempty,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS"`
+			RequestBody     string `json:"request_body"`
+			AuthType        string `json:"auth_type" validate:"omitempty,oneof=none basic bearer"`
+			AuthUsername    string `json:"auth_username"`
+			AuthPassword    string `json:"auth_password"`
+			AuthToken       string `json:"auth_token"`
+
+			ClientCertificate  string `json:"client_certificate"`
+			ClientKey          string `json:"client_key"`
+			CACertificates     string `json:"ca_certificates"`
+			InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+			ProxyURL           string `json:"proxy_url"`
+
+			EscalationPolicyID *uint `json:"escalation_policy_id"`
+
+			FlapDetection    *bool    `json:"flap_detection"` // defaults to enabled
+			FlapWindow       int      `json:"flap_window"`
+			FlapStartPercent float64  `json:"flap_start_percent"`
+			FlapStopPercent  *float64 `json:"flap_stop_percent"` // 0 is a valid threshold
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -62,11 +86,60 @@ func CreateMonitor(db *database.DB, monitorService *monitoring.Service) fiber.Ha
 			Name:            req.Name,
 			Type:            req.Type,
 			URL:             req.URL,
+			Group:           req.Group,
+			Tags:            strings.Join(monitoring.ParseTags(req.Tags), ","),
 			IntervalSeconds: req.IntervalSeconds,
 			TimeoutSeconds:  req.TimeoutSeconds,
 			ExpectedStatus:  req.ExpectedStatus,
 			CustomHeaders:   req.CustomHeaders,
+			Method:          req.Method,
+			RequestBody:     req.RequestBody,
+			AuthType:        req.AuthType,
+			AuthUsername:    req.AuthUsername,
+			AuthPassword:    req.AuthPassword,
+			AuthToken:       req.AuthToken,
 			IsActive:        true,
+
+			ClientCertificate:  req.ClientCertificate,
+			ClientKey:          req.ClientKey,
+			CACertificates:     req.CACertificates,
+			InsecureSkipVerify: req.InsecureSkipVerify,
+			ProxyURL:           req.ProxyURL,
+
+			EscalationPolicyID: req.EscalationPolicyID,
+
+			FlapDetection:    req.FlapDetection == nil || *req.FlapDetection,
+			FlapWindow:       req.FlapWindow,
+			FlapStartPercent: req.FlapStartPercent,
+		}
+		if monitor.FlapWindow == 0 {
+			monitor.FlapWindow = 20
+		}
+		if monitor.FlapStartPercent == 0 {
+			monitor.FlapStartPercent = 50
+		}
+		// The stop threshold defaults to half the start, 25% for the default start
+		monitor.FlapStopPercent = monitor.FlapStartPercent / 2
+		if req.FlapStopPercent != nil {
+			monitor.FlapStopPercent = *req.FlapStopPercent
+		}
+
+		if err := monitoring.ValidateTransportConfig(&monitor); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid TLS or proxy configuration: " + err.Error(),
+			})
+		}
+
+		if !escalationPolicyInOrganization(db, monitor.EscalationPolicyID, monitor.OrganizationID) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Escalation policy not found in the monitor's organization",
+			})
+		}
+
+		if err := monitoring.ValidateFlapDetection(&monitor); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
 		}
 
 		if err := db.Create(&monitor).Error; err != nil {
@@ -75,6 +148,11 @@ func CreateMonitor(db *database.DB, monitorService *monitoring.Service) fiber.Ha
 			})
 		}
 
+		// Create skips false in favour of the column default, so store an opt-out explicitly
+		if !monitor.FlapDetection {
+			db.Model(&monitor).Update("flap_detection", false)
+		}
+
 		// Schedule the monitor
 		monitorService.ScheduleMonitor(&monitor)
 
@@ -121,11 +199,32 @@ func UpdateMonitor(db *database.DB, monitorService *monitoring.Service) fiber.Ha
 			Name            string `json:"name" validate:"required"`
 			Type            string `json:"type" validate:"required,oneof=http ssl webhook"`
 			URL             string `json:"url" validate:"required"`
+			Group           string `json:"group"`
+			Tags            string `json:"tags"` // comma separated, used by maintenance windows
 			IntervalSeconds int    `json:"interval_seconds" validate:"required,min=30"`
 			TimeoutSeconds  int    `json:"timeout_seconds" validate:"required,min=5"`
 			ExpectedStatus  int    `json:"expected_status" validate:"required,min=100,max=599"`
 			CustomHeaders   string `json:"custom_headers"`
+			Method          string `json:"method" validate:"omitempty,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS"`
+			RequestBody     string `json:"request_body"`
+			AuthType        string `json:"auth_type" validate:"omitempty,oneof=none basic bearer"`
+			AuthUsername    string `json:"auth_username"`
+			AuthPassword    string `json:"auth_password"`
+			AuthToken       string `json:"auth_token"`
 			IsActive        bool   `json:"is_active"`
+
+			ClientCertificate  string `json:"client_certificate"`
+			ClientKey          string `json:"client_key"` // leave empty to keep the stored key
+			CACertificates     string `json:"ca_certificates"`
+			InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+			ProxyURL           string `json:"proxy_url"`
+
+			EscalationPolicyID *uint `json:"escalation_policy_id"`
+
+			FlapDetection    *bool    `json:"flap_detection"` // defaults to enabled
+			FlapWindow       int      `json:"flap_window"`
+			FlapStartPercent float64  `json:"flap_start_percent"`
+			FlapStopPercent  *float64 `json:"flap_stop_percent"` // 0 is a valid threshold
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -146,11 +245,77 @@ func UpdateMonitor(db *database.DB, monitorService *monitoring.Service) fiber.Ha
 		monitor.Name = req.Name
 		monitor.Type = req.Type
 		monitor.URL = req.URL
+		monitor.Group = req.Group
+		monitor.Tags = strings.Join(monitoring.ParseTags(req.Tags), ",")
 		monitor.IntervalSeconds = req.IntervalSeconds
 		monitor.TimeoutSeconds = req.TimeoutSeconds
 		monitor.ExpectedStatus = req.ExpectedStatus
-		monitor.CustomHeaders = req.CustomHeaders
+		monitor.CustomHeaders = mergeMaskedHeaders(monitor.CustomHeaders, req.CustomHeaders)
+		monitor.Method = req.Method
+		monitor.RequestBody = req.RequestBody
+		monitor.AuthType = req.AuthType
+		monitor.AuthUsername = req.AuthUsername
 		monitor.IsActive = req.IsActive
+		monitor.ClientCertificate = req.ClientCertificate
+		monitor.CACertificates = req.CACertificates
+		monitor.InsecureSkipVerify = req.InsecureSkipVerify
+		// A masked proxy URL sent back unchanged keeps the stored password
+		if req.ProxyURL != database.MaskProxyURL(monitor.ProxyURL) {
+			monitor.ProxyURL = req.ProxyURL
+		}
+		monitor.EscalationPolicyID = req.EscalationPolicyID
+		if req.FlapDetection != nil {
+			monitor.FlapDetection = *req.FlapDetection
+		}
+		if req.FlapWindow != 0 {
+			monitor.FlapWindow = req.FlapWindow
+		}
+		if req.FlapStartPercent != 0 {
+			monitor.FlapStartPercent = req.FlapStartPercent
+		}
+		if req.FlapStopPercent != nil {
+			monitor.FlapStopPercent = *req.FlapStopPercent
+		}
+
+		// Credentials are never returned, so only replace them when new ones are sent
+		if req.AuthPassword != "" {
+			monitor.AuthPassword = req.AuthPassword
+		}
+		if req.AuthToken != "" {
+			monitor.AuthToken = req.AuthToken
+		}
+		if monitor.AuthType != "basic" {
+			monitor.AuthPassword = ""
+		}
+		if monitor.AuthType != "bearer" {
+			monitor.AuthToken = ""
+		}
+
+		// The client key is never returned, so only replace it when a new one is sent
+		if req.ClientKey != "" {
+			monitor.ClientKey = req.ClientKey
+		}
+		if req.ClientCertificate == "" {
+			monitor.ClientKey = ""
+		}
+
+		if err := monitoring.ValidateTransportConfig(&monitor); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid TLS or proxy configuration: " + err.Error(),
+			})
+		}
+
+		if !escalationPolicyInOrganization(db, monitor.EscalationPolicyID, monitor.OrganizationID) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Escalation policy not found in the monitor's organization",
+			})
+		}
+
+		if err := monitoring.ValidateFlapDetection(&monitor); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 
 		if err := db.Save(&monitor).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{
@@ -185,7 +350,16 @@ func DeleteMonitor(db *database.DB, monitorService *monitoring.Service) fiber.Ha
 			})
 		}
 
-		if err := db.Delete(&monitor).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("monitor_id = ? OR parent_id = ?", monitor.ID, monitor.ID).
+				Delete(&database.MonitorDependency{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("monitor_id = ?", monitor.ID).Delete(&database.AlertRule{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&monitor).Error
+		}); err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": "Failed to delete monitor",
 			})
@@ -250,3 +424,47 @@ func GetMonitorStatus(monitorService *monitoring.Service) fiber.Handler {
 		return c.JSON(status)
 	}
 }
+
+// escalationPolicyInOrganization reports whether an optional escalation policy belongs to an organization
+func escalationPolicyInOrganization(db *database.DB, policyID *uint, organizationID uint) bool {
+	if policyID == nil {
+		return true
+	}
+
+	var count int64
+	db.Model(&database.EscalationPolicy{}).Where("id = ? AND organization_id = ?", *policyID, organizationID).Count(&count)
+	return count > 0
+}
+
+// mergeMaskedHeaders keeps the stored value of every header sent back masked, as returned
+// by the API, so clients can edit headers without resending credentials
+func mergeMaskedHeaders(stored, requested string) string {
+	if requested == database.MaskedValue {
+		return stored
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal([]byte(requested), &values); err != nil {
+		return requested
+	}
+
+	var storedValues map[string]string
+	json.Unmarshal([]byte(stored), &storedValues)
+
+	masked := false
+	for name, value := range values {
+		if value == database.MaskedValue {
+			values[name] = storedValues[name]
+			masked = true
+		}
+	}
+	if !masked {
+		return requested
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return requested
+	}
+	return string(encoded)
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 
 	"vigil/internal/database"
+	"vigil/internal/monitoring"
 )
 
 // GetNotificationChannels returns all notification channels for the current user's organizations
@@ -33,8 +36,11 @@ func CreateNotificationChannel(db *database.DB) fiber.Handler {
 
 		var req struct {
 			OrganizationID uint   `json:"organization_id" validate:"required"`
-			Type           string `json:"type" validate:"required,oneof=email slack discord webhook"`
+			Type           string `json:"type" validate:"required,oneof=email slack discord webhook on_call"`
 			Config         string `json:"config" validate:"required"`
+
+			ReminderIntervalMinutes int `json:"reminder_interval_minutes"` // 0 turns reminders off
+			MaxReminders            int `json:"max_reminders"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -51,11 +57,26 @@ func CreateNotificationChannel(db *database.DB) fiber.Handler {
 			})
 		}
 
+		if err := monitoring.ValidateReminders(req.ReminderIntervalMinutes, req.MaxReminders); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := validateOnCallChannel(db, req.OrganizationID, req.Type, req.Config); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
 		channel := database.NotificationChannel{
 			OrganizationID: req.OrganizationID,
 			Type:           req.Type,
 			Config:         req.Config,
 			IsActive:       true,
+
+			ReminderIntervalMinutes: req.ReminderIntervalMinutes,
+			MaxReminders:            req.MaxReminders,
 		}
 
 		if err := db.Create(&channel).Error; err != nil {
@@ -104,9 +125,12 @@ func UpdateNotificationChannel(db *database.DB) fiber.Handler {
 		}
 
 		var req struct {
-			Type     string `json:"type" validate:"required,oneof=email slack discord webhook"`
+			Type     string `json:"type" validate:"required,oneof=email slack discord webhook on_call"`
 			Config   string `json:"config" validate:"required"`
 			IsActive bool   `json:"is_active"`
+
+			ReminderIntervalMinutes int `json:"reminder_interval_minutes"` // 0 turns reminders off
+			MaxReminders            int `json:"max_reminders"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -127,6 +151,20 @@ func UpdateNotificationChannel(db *database.DB) fiber.Handler {
 		channel.Type = req.Type
 		channel.Config = req.Config
 		channel.IsActive = req.IsActive
+		channel.ReminderIntervalMinutes = req.ReminderIntervalMinutes
+		channel.MaxReminders = req.MaxReminders
+
+		if err := monitoring.ValidateReminders(channel.ReminderIntervalMinutes, channel.MaxReminders); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := validateOnCallChannel(db, channel.OrganizationID, channel.Type, channel.Config); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 
 		if err := db.Save(&channel).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{
@@ -167,3 +205,26 @@ func DeleteNotificationChannel(db *database.DB) fiber.Handler {
 		return c.SendStatus(204)
 	}
 }
+
+// validateOnCallChannel checks that an on_call channel pages a schedule of its own organization
+func validateOnCallChannel(db *database.DB, organizationID uint, channelType, config string) error {
+	if channelType != "on_call" {
+		return nil
+	}
+
+	var settings struct {
+		ScheduleID uint `json:"scheduleId"`
+	}
+	if err := json.Unmarshal([]byte(config), &settings); err != nil || settings.ScheduleID == 0 {
+		return fmt.Errorf("on_call channels need a config with a scheduleId")
+	}
+
+	var count int64
+	db.Model(&database.OnCallSchedule{}).
+		Where("id = ? AND organization_id = ?", settings.ScheduleID, organizationID).
+		Count(&count)
+	if count == 0 {
+		return fmt.Errorf("on-call schedule %d not found", settings.ScheduleID)
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// onCallScheduleRequest is the body accepted when creating or updating a schedule
+type onCallScheduleRequest struct {
+	OrganizationID uint      `json:"organization_id"` // only read on create
+	Name           string    `json:"name" validate:"required"`
+	Description    string    `json:"description"`
+	Timezone       string    `json:"timezone"`
+	RotationType   string    `json:"rotation_type" validate:"required,oneof=daily weekly"`
+	RotationStart  time.Time `json:"rotation_start" validate:"required"`
+	UserIDs        []uint    `json:"user_ids" validate:"required"` // rotation order
+}
+
+// GetOnCallSchedules returns all on-call schedules for the current user's organizations
+func GetOnCallSchedules(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var schedules []database.OnCallSchedule
+		if err := monitoring.PreloadOnCall(db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID), time.Now()).
+			Find(&schedules).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch on-call schedules",
+			})
+		}
+
+		return c.JSON(schedules)
+	}
+}
+
+// CreateOnCallSchedule creates an on-call schedule
+func CreateOnCallSchedule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req onCallScheduleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		// Verify user owns the organization
+		var organization database.Organization
+		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Organization not found",
+			})
+		}
+
+		schedule := database.OnCallSchedule{OrganizationID: organization.ID}
+		if err := applyOnCallSchedule(db, &schedule, &req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Create(&schedule).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create on-call schedule",
+			})
+		}
+
+		return c.Status(201).JSON(schedule)
+	}
+}
+
+// GetOnCallSchedule returns a specific on-call schedule with its upcoming overrides
+func GetOnCallSchedule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		scheduleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid schedule ID",
+			})
+		}
+
+		var schedule database.OnCallSchedule
+		if err := monitoring.PreloadOnCall(db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("on_call_schedules.id = ? AND organizations.owner_id = ?", scheduleID, userID), time.Now()).
+			First(&schedule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "On-call schedule not found",
+			})
+		}
+
+		return c.JSON(schedule)
+	}
+}
+
+// UpdateOnCallSchedule updates an on-call schedule, replacing its rotation
+func UpdateOnCallSchedule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		scheduleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid schedule ID",
+			})
+		}
+
+		var req onCallScheduleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var schedule database.OnCallSchedule
+		if err := db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("on_call_schedules.id = ? AND organizations.owner_id = ?", scheduleID, userID).
+			First(&schedule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "On-call schedule not found",
+			})
+		}
+
+		if err := applyOnCallSchedule(db, &schedule, &req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&database.OnCallParticipant{}).Error; err != nil {
+				return err
+			}
+			for i := range schedule.Participants {
+				schedule.Participants[i].ScheduleID = schedule.ID
+			}
+			if err := tx.Create(&schedule.Participants).Error; err != nil {
+				return err
+			}
+			return tx.Omit("Participants", "Overrides").Save(&schedule).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update on-call schedule",
+			})
+		}
+
+		return c.JSON(schedule)
+	}
+}
+
+// DeleteOnCallSchedule deletes an on-call schedule along with its rotation, overrides and
+// the escalation targets that page it
+func DeleteOnCallSchedule(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		scheduleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid schedule ID",
+			})
+		}
+
+		var schedule database.OnCallSchedule
+		if err := db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("on_call_schedules.id = ? AND organizations.owner_id = ?", scheduleID, userID).
+			First(&schedule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "On-call schedule not found",
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&database.EscalationTarget{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&database.OnCallOverride{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&database.OnCallParticipant{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schedule).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete on-call schedule",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// CreateOnCallOverride puts a member on call for a period, overriding the rotation
+func CreateOnCallOverride(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		scheduleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid schedule ID",
+			})
+		}
+
+		var req struct {
+			UserID   uint      `json:"user_id" validate:"required"`
+			StartsAt time.Time `json:"starts_at" validate:"required"`
+			EndsAt   time.Time `json:"ends_at" validate:"required"`
+			Reason   string    `json:"reason"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if req.StartsAt.IsZero() || !req.EndsAt.After(req.StartsAt) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "ends_at must be after starts_at",
+			})
+		}
+
+		var schedule database.OnCallSchedule
+		if err := db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("on_call_schedules.id = ? AND organizations.owner_id = ?", scheduleID, userID).
+			First(&schedule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "On-call schedule not found",
+			})
+		}
+
+		if !isOrganizationMember(db, schedule.OrganizationID, req.UserID) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Override user must be a member of the schedule's organization",
+			})
+		}
+
+		override := database.OnCallOverride{
+			ScheduleID:  schedule.ID,
+			UserID:      req.UserID,
+			StartsAt:    req.StartsAt,
+			EndsAt:      req.EndsAt,
+			Reason:      req.Reason,
+			CreatedByID: userID,
+		}
+
+		if err := db.Create(&override).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create override",
+			})
+		}
+
+		return c.Status(201).JSON(override)
+	}
+}
+
+// DeleteOnCallOverride removes an override from a schedule
+func DeleteOnCallOverride(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		scheduleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid schedule ID",
+			})
+		}
+
+		overrideID, err := strconv.ParseUint(c.Params("overrideId"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid override ID",
+			})
+		}
+
+		var override database.OnCallOverride
+		if err := db.Joins("JOIN on_call_schedules ON on_call_overrides.schedule_id = on_call_schedules.id").
+			Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("on_call_overrides.id = ? AND on_call_schedules.id = ? AND organizations.owner_id = ?", overrideID, scheduleID, userID).
+			First(&override).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Override not found",
+			})
+		}
+
+		if err := db.Delete(&override).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete override",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// GetOnCall returns who is on call on a schedule, now or at the time given by ?at=
+func GetOnCall(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		scheduleID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid schedule ID",
+			})
+		}
+
+		at := time.Now()
+		if value := c.Query("at"); value != "" {
+			if at, err = time.Parse(time.RFC3339, value); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "at must be an RFC 3339 timestamp",
+				})
+			}
+		}
+
+		var schedule database.OnCallSchedule
+		if err := monitoring.PreloadOnCall(db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("on_call_schedules.id = ? AND organizations.owner_id = ?", scheduleID, userID), at).
+			First(&schedule).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "On-call schedule not found",
+			})
+		}
+
+		return c.JSON(monitoring.ResolveOnCall(&schedule, at))
+	}
+}
+
+// GetOnCallNow returns who is on call right now on every schedule of the current user's organizations
+func GetOnCallNow(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		now := time.Now()
+
+		var schedules []database.OnCallSchedule
+		if err := monitoring.PreloadOnCall(db.Joins("JOIN organizations ON on_call_schedules.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID), now).
+			Find(&schedules).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch on-call schedules",
+			})
+		}
+
+		shifts := make([]fiber.Map, 0, len(schedules))
+		for i := range schedules {
+			shifts = append(shifts, fiber.Map{
+				"schedule_id":   schedules[i].ID,
+				"schedule_name": schedules[i].Name,
+				"on_call":       monitoring.ResolveOnCall(&schedules[i], now),
+			})
+		}
+
+		return c.JSON(shifts)
+	}
+}
+
+// applyOnCallSchedule copies a request onto a schedule and validates the result
+func applyOnCallSchedule(db *database.DB, schedule *database.OnCallSchedule, req *onCallScheduleRequest) error {
+	schedule.Name = req.Name
+	schedule.Description = req.Description
+	schedule.Timezone = req.Timezone
+	schedule.RotationType = req.RotationType
+	schedule.RotationStart = req.RotationStart
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+
+	schedule.Participants = nil
+	for i, participantID := range req.UserIDs {
+		if !isOrganizationMember(db, schedule.OrganizationID, participantID) {
+			return fmt.Errorf("user %d is not a member of the organization", participantID)
+		}
+		schedule.Participants = append(schedule.Participants, database.OnCallParticipant{
+			Position: i + 1,
+			UserID:   participantID,
+		})
+	}
+
+	return monitoring.ValidateOnCallSchedule(schedule)
+}
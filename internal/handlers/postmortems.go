@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// postmortemSections are the Markdown sections of a postmortem as sent by clients
+type postmortemSections struct {
+	Title          string `json:"title" validate:"required"`
+	Summary        string `json:"summary"`
+	Impact         string `json:"impact"`
+	RootCause      string `json:"root_cause"`
+	Resolution     string `json:"resolution"`
+	LessonsLearned string `json:"lessons_learned"`
+}
+
+// postmortemsForOwner scopes a postmortems query to the user's organizations
+func postmortemsForOwner(db *database.DB, userID uint) *gorm.DB {
+	return db.Joins("JOIN organizations ON postmortems.organization_id = organizations.id").
+		Where("organizations.owner_id = ?", userID)
+}
+
+// GetPostmortems returns the postmortems of the current user's organizations
+func GetPostmortems(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var postmortems []database.Postmortem
+		if err := postmortemsForOwner(db, userID).
+			Order("postmortems.created_at DESC").
+			Find(&postmortems).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch postmortems",
+			})
+		}
+
+		return c.JSON(postmortems)
+	}
+}
+
+// CreatePostmortem starts a postmortem for an incident or an alert
+func CreatePostmortem(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req struct {
+			postmortemSections
+			IncidentID  *uint      `json:"incident_id"`
+			AlertID     *uint      `json:"alert_id"`
+			WindowStart *time.Time `json:"window_start"`
+			WindowEnd   *time.Time `json:"window_end"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if (req.IncidentID == nil) == (req.AlertID == nil) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Exactly one of incident_id or alert_id is required",
+			})
+		}
+
+		postmortem := database.Postmortem{
+			Status:   "draft",
+			AuthorID: userID,
+		}
+
+		if req.IncidentID != nil {
+			var incident database.Incident
+			if err := db.Joins("JOIN organizations ON incidents.organization_id = organizations.id").
+				Where("incidents.id = ? AND organizations.owner_id = ?", *req.IncidentID, userID).
+				First(&incident).Error; err != nil {
+				return c.Status(404).JSON(fiber.Map{
+					"error": "Incident not found",
+				})
+			}
+			postmortem.OrganizationID = incident.OrganizationID
+			postmortem.IncidentID = &incident.ID
+			postmortem.WindowStart, postmortem.WindowEnd = monitoring.PostmortemWindow(&incident, nil)
+			if req.Title == "" {
+				req.Title = incident.Title
+			}
+		} else {
+			var alert database.Alert
+			if err := alertsForOwner(db, userID).
+				Where("alerts.id = ?", *req.AlertID).
+				First(&alert).Error; err != nil {
+				return c.Status(404).JSON(fiber.Map{
+					"error": "Alert not found",
+				})
+			}
+
+			var organizationIDs []uint
+			alertsForOwner(db, userID).Model(&database.Alert{}).
+				Where("alerts.id = ?", alert.ID).
+				Pluck("organizations.id", &organizationIDs)
+			if len(organizationIDs) == 0 {
+				return c.Status(404).JSON(fiber.Map{
+					"error": "Alert not found",
+				})
+			}
+
+			postmortem.OrganizationID = organizationIDs[0]
+			postmortem.AlertID = &alert.ID
+			postmortem.WindowStart, postmortem.WindowEnd = monitoring.PostmortemWindow(nil, &alert)
+			if req.Title == "" {
+				req.Title = alert.Message
+			}
+		}
+
+		if req.WindowStart != nil {
+			postmortem.WindowStart = *req.WindowStart
+		}
+		if req.WindowEnd != nil {
+			postmortem.WindowEnd = *req.WindowEnd
+		}
+		if !postmortem.WindowEnd.After(postmortem.WindowStart) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "window_end must be after window_start",
+			})
+		}
+
+		applyPostmortemSections(&postmortem, &req.postmortemSections)
+
+		if err := db.Create(&postmortem).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create postmortem",
+			})
+		}
+
+		return c.Status(201).JSON(postmortem)
+	}
+}
+
+// GetPostmortem returns a postmortem with its action items and generated timeline
+func GetPostmortem(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		postmortem, status, message := loadPostmortem(c, db)
+		if postmortem == nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		timeline, err := monitorService.PostmortemTimeline(postmortem)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to build postmortem timeline",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"postmortem": postmortem,
+			"timeline":   timeline,
+		})
+	}
+}
+
+// ExportPostmortem returns a postmortem as a Markdown document
+func ExportPostmortem(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		postmortem, status, message := loadPostmortem(c, db)
+		if postmortem == nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		timeline, err := monitorService.PostmortemTimeline(postmortem)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to build postmortem timeline",
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="postmortem-%d.md"`, postmortem.ID))
+		return c.SendString(monitoring.RenderPostmortemMarkdown(postmortem, timeline))
+	}
+}
+
+// UpdatePostmortem updates a postmortem's sections, status and window
+func UpdatePostmortem(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		postmortemID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid postmortem ID",
+			})
+		}
+
+		var req struct {
+			postmortemSections
+			Status      string     `json:"status" validate:"omitempty,oneof=draft published"`
+			WindowStart *time.Time `json:"window_start"`
+			WindowEnd   *time.Time `json:"window_end"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var postmortem database.Postmortem
+		if err := postmortemsForOwner(db, userID).
+			Where("postmortems.id = ?", postmortemID).
+			First(&postmortem).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Postmortem not found",
+			})
+		}
+
+		switch req.Status {
+		case "":
+		case "draft", "published":
+			postmortem.Status = req.Status
+		default:
+			return c.Status(400).JSON(fiber.Map{
+				"error": "status must be draft or published",
+			})
+		}
+
+		if req.WindowStart != nil {
+			postmortem.WindowStart = *req.WindowStart
+		}
+		if req.WindowEnd != nil {
+			postmortem.WindowEnd = *req.WindowEnd
+		}
+		if !postmortem.WindowEnd.After(postmortem.WindowStart) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "window_end must be after window_start",
+			})
+		}
+
+		if req.Title == "" {
+			req.Title = postmortem.Title
+		}
+		applyPostmortemSections(&postmortem, &req.postmortemSections)
+
+		if err := db.Save(&postmortem).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update postmortem",
+			})
+		}
+
+		return c.JSON(postmortem)
+	}
+}
+
+// DeletePostmortem deletes a postmortem and its action items
+func DeletePostmortem(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		postmortemID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid postmortem ID",
+			})
+		}
+
+		var postmortem database.Postmortem
+		if err := postmortemsForOwner(db, userID).
+			Where("postmortems.id = ?", postmortemID).
+			First(&postmortem).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Postmortem not found",
+			})
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("postmortem_id = ?", postmortem.ID).Delete(&database.PostmortemActionItem{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&postmortem).Error
+		}); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete postmortem",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// CreatePostmortemActionItem adds an action item to a postmortem
+func CreatePostmortemActionItem(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		postmortemID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid postmortem ID",
+			})
+		}
+
+		var req struct {
+			Description string     `json:"description" validate:"required"`
+			OwnerID     *uint      `json:"owner_id"`
+			DueDate     *time.Time `json:"due_date"`
+		}
+
+		if err := c.BodyParser(&req); err != nil || req.Description == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var postmortem database.Postmortem
+		if err := postmortemsForOwner(db, userID).
+			Where("postmortems.id = ?", postmortemID).
+			First(&postmortem).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Postmortem not found",
+			})
+		}
+
+		if req.OwnerID != nil && !isOrganizationMember(db, postmortem.OrganizationID, *req.OwnerID) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Owner must be a member of the postmortem's organization",
+			})
+		}
+
+		item := database.PostmortemActionItem{
+			PostmortemID: postmortem.ID,
+			Description:  req.Description,
+			OwnerID:      req.OwnerID,
+			DueDate:      req.DueDate,
+			Status:       "open",
+		}
+
+		if err := db.Create(&item).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create action item",
+			})
+		}
+
+		return c.Status(201).JSON(item)
+	}
+}
+
+// UpdatePostmortemActionItem updates an action item, e.g. to mark it done
+func UpdatePostmortemActionItem(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		item, status, message := loadPostmortemActionItem(c, db, userID)
+		if item == nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		var req struct {
+			Description string     `json:"description" validate:"required"`
+			OwnerID     *uint      `json:"owner_id"`
+			DueDate     *time.Time `json:"due_date"`
+			Status      string     `json:"status" validate:"required,oneof=open done"`
+		}
+
+		if err := c.BodyParser(&req); err != nil || req.Description == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if req.Status != "open" && req.Status != "done" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "status must be open or done",
+			})
+		}
+
+		var postmortem database.Postmortem
+		if err := db.First(&postmortem, item.PostmortemID).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Postmortem not found",
+			})
+		}
+
+		if req.OwnerID != nil && !isOrganizationMember(db, postmortem.OrganizationID, *req.OwnerID) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Owner must be a member of the postmortem's organization",
+			})
+		}
+
+		if req.Status == "done" && item.Status != "done" {
+			now := time.Now()
+			item.CompletedAt = &now
+		} else if req.Status == "open" {
+			item.CompletedAt = nil
+		}
+
+		item.Description = req.Description
+		item.OwnerID = req.OwnerID
+		item.DueDate = req.DueDate
+		item.Status = req.Status
+
+		if err := db.Save(item).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update action item",
+			})
+		}
+
+		return c.JSON(item)
+	}
+}
+
+// DeletePostmortemActionItem removes an action item from a postmortem
+func DeletePostmortemActionItem(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		item, status, message := loadPostmortemActionItem(c, db, userID)
+		if item == nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+
+		if err := db.Delete(item).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete action item",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
+
+// loadPostmortem loads the postmortem named by the :id param with its author and action
+// items. On failure it returns nil with the status and error message to respond with.
+func loadPostmortem(c *fiber.Ctx, db *database.DB) (*database.Postmortem, int, string) {
+	userID := c.Locals("user_id").(uint)
+	postmortemID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, 400, "Invalid postmortem ID"
+	}
+
+	var postmortem database.Postmortem
+	if err := postmortemsForOwner(db, userID).
+		Where("postmortems.id = ?", postmortemID).
+		Preload("Author").
+		Preload("ActionItems", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("ActionItems.Owner").
+		First(&postmortem).Error; err != nil {
+		return nil, 404, "Postmortem not found"
+	}
+
+	return &postmortem, 0, ""
+}
+
+// loadPostmortemActionItem loads the action item named by the :id and :itemId params
+func loadPostmortemActionItem(c *fiber.Ctx, db *database.DB, userID uint) (*database.PostmortemActionItem, int, string) {
+	postmortemID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return nil, 400, "Invalid postmortem ID"
+	}
+
+	itemID, err := strconv.ParseUint(c.Params("itemId"), 10, 32)
+	if err != nil {
+		return nil, 400, "Invalid action item ID"
+	}
+
+	var item database.PostmortemActionItem
+	if err := db.Joins("JOIN postmortems ON postmortem_action_items.postmortem_id = postmortems.id").
+		Joins("JOIN organizations ON postmortems.organization_id = organizations.id").
+		Where("postmortem_action_items.id = ? AND postmortems.id = ? AND organizations.owner_id = ?", itemID, postmortemID, userID).
+		First(&item).Error; err != nil {
+		return nil, 404, "Action item not found"
+	}
+
+	return &item, 0, ""
+}
+
+// applyPostmortemSections copies the Markdown sections of a request onto a postmortem
+func applyPostmortemSections(postmortem *database.Postmortem, sections *postmortemSections) {
+	postmortem.Title = sections.Title
+	postmortem.Summary = sections.Summary
+	postmortem.Impact = sections.Impact
+	postmortem.RootCause = sections.RootCause
+	postmortem.Resolution = sections.Resolution
+	postmortem.LessonsLearned = sections.LessonsLearned
+}
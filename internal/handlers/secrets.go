@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vigil/internal/database"
+)
+
+// secretNamePattern matches names usable in {{secrets.NAME}} placeholders
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// GetSecrets returns the secret names for the current user's organizations; values are never returned
+func GetSecrets(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		query := db.Joins("JOIN organizations ON organization_secrets.organization_id = organizations.id").
+			Where("organizations.owner_id = ?", userID)
+
+		if orgID := c.Query("organization_id"); orgID != "" {
+			query = query.Where("organization_secrets.organization_id = ?", orgID)
+		}
+
+		var secrets []database.OrganizationSecret
+		if err := query.Order("organization_secrets.name").Find(&secrets).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch secrets",
+			})
+		}
+
+		return c.JSON(secrets)
+	}
+}
+
+// CreateSecret creates a new organization secret
+func CreateSecret(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var req struct {
+			OrganizationID uint   `json:"organization_id" validate:"required"`
+			Name           string `json:"name" validate:"required"`
+			Value          string `json:"value" validate:"required"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if !secretNamePattern.MatchString(req.Name) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Secret names may only contain letters, digits and underscores",
+			})
+		}
+
+		// Verify user owns the organization
+		var organization database.Organization
+		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Organization not found",
+			})
+		}
+
+		var existing database.OrganizationSecret
+		if err := db.Where("organization_id = ? AND name = ?", req.OrganizationID, req.Name).First(&existing).Error; err == nil {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Secret already exists",
+			})
+		}
+
+		secret := database.OrganizationSecret{
+			OrganizationID: req.OrganizationID,
+			Name:           req.Name,
+			Value:          req.Value,
+		}
+
+		if err := db.Create(&secret).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create secret",
+			})
+		}
+
+		return c.Status(201).JSON(secret)
+	}
+}
+
+// UpdateSecret replaces the value of an organization secret
+func UpdateSecret(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		secretID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid secret ID",
+			})
+		}
+
+		var req struct {
+			Value string `json:"value" validate:"required"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var secret database.OrganizationSecret
+		if err := db.Joins("JOIN organizations ON organization_secrets.organization_id = organizations.id").
+			Where("organization_secrets.id = ? AND organizations.owner_id = ?", secretID, userID).
+			First(&secret).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Secret not found",
+			})
+		}
+
+		secret.Value = req.Value
+
+		if err := db.Save(&secret).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update secret",
+			})
+		}
+
+		return c.JSON(secret)
+	}
+}
+
+// DeleteSecret deletes an organization secret
+func DeleteSecret(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		secretID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid secret ID",
+			})
+		}
+
+		var secret database.OrganizationSecret
+		if err := db.Joins("JOIN organizations ON organization_secrets.organization_id = organizations.id").
+			Where("organization_secrets.id = ? AND organizations.owner_id = ?", secretID, userID).
+			First(&secret).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Secret not found",
+			})
+		}
+
+		if err := db.Delete(&secret).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete secret",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
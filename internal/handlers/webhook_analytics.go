@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vigil/internal/database"
+)
+
+// maxAnalyticsBuckets bounds how many buckets one analytics request may span
+const maxAnalyticsBuckets = 1000
+
+// analyticsBucket is one time bucket of webhook delivery analytics
+type analyticsBucket struct {
+	Bucket       time.Time `json:"bucket"`
+	Total        int64     `json:"total"`
+	Succeeded    int64     `json:"succeeded"`
+	Failed       int64     `json:"failed"`
+	Rejected     int64     `json:"rejected"`
+	SuccessRate  float64   `json:"success_rate"`
+	Attempts     int64     `json:"attempts"`
+	LatencyP50Ms *float64  `json:"latency_p50_ms"`
+	LatencyP90Ms *float64  `json:"latency_p90_ms"`
+	LatencyP99Ms *float64  `json:"latency_p99_ms"`
+}
+
+// latencyRow holds forward latency percentiles, overall or for one bucket
+type latencyRow struct {
+	Bucket       time.Time
+	Attempts     int64
+	LatencyP50Ms *float64
+	LatencyP90Ms *float64
+	LatencyP99Ms *float64
+}
+
+// GetWebhookAnalytics returns delivery volume, outcome rates, retry distribution and
+// forward latency percentiles for a webhook, bucketed by hour or day
+func GetWebhookAnalytics(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		bucket := c.Query("bucket", "hour")
+		var bucketSize time.Duration
+		switch bucket {
+		case "hour":
+			bucketSize = time.Hour
+		case "day":
+			bucketSize = 24 * time.Hour
+		default:
+			return c.Status(400).JSON(fiber.Map{
+				"error": "bucket must be hour or day",
+			})
+		}
+
+		to := time.Now()
+		if value := c.Query("to"); value != "" {
+			if to, err = time.Parse(time.RFC3339, value); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "to must be an RFC 3339 timestamp",
+				})
+			}
+		}
+
+		from := to.AddDate(0, 0, -7)
+		if value := c.Query("from"); value != "" {
+			if from, err = time.Parse(time.RFC3339, value); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "from must be an RFC 3339 timestamp",
+				})
+			}
+		}
+
+		if !to.After(from) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "to must be after from",
+			})
+		}
+
+		if to.Sub(from)/bucketSize > maxAnalyticsBuckets {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Range is too large for the requested bucket size",
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		// Volume and outcomes per bucket
+		var buckets []analyticsBucket
+		if err := db.Raw(`
+			SELECT date_trunc(?, delivered_at) AS bucket,
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE status = 'success') AS succeeded,
+				COUNT(*) FILTER (WHERE status IN ('failed', 'dead_letter')) AS failed,
+				COUNT(*) FILTER (WHERE status = 'rejected') AS rejected
+			FROM webhook_deliveries
+			WHERE webhook_id = ? AND delivered_at >= ? AND delivered_at < ?
+			GROUP BY 1
+			ORDER BY 1`, bucket, webhook.ID, from, to).
+			Scan(&buckets).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to compute delivery volume",
+			})
+		}
+
+		// Forward latency per bucket, from every attempt made in the range
+		latencyQuery := `
+			SELECT %s
+				COUNT(*) AS attempts,
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY a.duration_ms) AS latency_p50_ms,
+				percentile_cont(0.9) WITHIN GROUP (ORDER BY a.duration_ms) AS latency_p90_ms,
+				percentile_cont(0.99) WITHIN GROUP (ORDER BY a.duration_ms) AS latency_p99_ms
+			FROM webhook_delivery_attempts a
+			JOIN webhook_deliveries d ON a.webhook_delivery_id = d.id
+			WHERE d.webhook_id = ? AND a.attempted_at >= ? AND a.attempted_at < ?
+			%s`
+
+		var latencies []latencyRow
+		if err := db.Raw(fmt.Sprintf(latencyQuery, "date_trunc(?, a.attempted_at) AS bucket,", "GROUP BY 1 ORDER BY 1"),
+			bucket, webhook.ID, from, to).
+			Scan(&latencies).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to compute forward latency",
+			})
+		}
+
+		var overallLatency latencyRow
+		if err := db.Raw(fmt.Sprintf(latencyQuery, "", ""), webhook.ID, from, to).
+			Scan(&overallLatency).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to compute forward latency",
+			})
+		}
+
+		// Merge latency into the volume buckets, adding buckets that only saw retries
+		index := make(map[int64]int, len(buckets))
+		for i := range buckets {
+			index[buckets[i].Bucket.Unix()] = i
+		}
+		for _, latency := range latencies {
+			i, ok := index[latency.Bucket.Unix()]
+			if !ok {
+				buckets = append(buckets, analyticsBucket{Bucket: latency.Bucket})
+				i = len(buckets) - 1
+				index[latency.Bucket.Unix()] = i
+			}
+			buckets[i].Attempts = latency.Attempts
+			buckets[i].LatencyP50Ms = latency.LatencyP50Ms
+			buckets[i].LatencyP90Ms = latency.LatencyP90Ms
+			buckets[i].LatencyP99Ms = latency.LatencyP99Ms
+		}
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].Bucket.Before(buckets[j].Bucket) })
+
+		var total, succeeded, failed, rejected int64
+		for i := range buckets {
+			buckets[i].SuccessRate = successRate(buckets[i].Succeeded, buckets[i].Failed)
+			total += buckets[i].Total
+			succeeded += buckets[i].Succeeded
+			failed += buckets[i].Failed
+			rejected += buckets[i].Rejected
+		}
+
+		// How many retries deliveries needed
+		var retries []struct {
+			RetryCount int   `json:"retry_count"`
+			Deliveries int64 `json:"deliveries"`
+		}
+		if err := db.Raw(`
+			SELECT retry_count, COUNT(*) AS deliveries
+			FROM webhook_deliveries
+			WHERE webhook_id = ? AND delivered_at >= ? AND delivered_at < ?
+			GROUP BY retry_count
+			ORDER BY retry_count`, webhook.ID, from, to).
+			Scan(&retries).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to compute retry distribution",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"webhook_id": webhook.ID,
+			"from":       from,
+			"to":         to,
+			"bucket":     bucket,
+			"summary": fiber.Map{
+				"total":          total,
+				"succeeded":      succeeded,
+				"failed":         failed,
+				"rejected":       rejected,
+				"success_rate":   successRate(succeeded, failed),
+				"attempts":       overallLatency.Attempts,
+				"latency_p50_ms": overallLatency.LatencyP50Ms,
+				"latency_p90_ms": overallLatency.LatencyP90Ms,
+				"latency_p99_ms": overallLatency.LatencyP99Ms,
+			},
+			"retry_distribution": retries,
+			"buckets":            buckets,
+		})
+	}
+}
+
+// successRate returns the percentage of finished deliveries that succeeded
+func successRate(succeeded, failed int64) float64 {
+	if succeeded+failed == 0 {
+		return 0
+	}
+	return float64(succeeded) / float64(succeeded+failed) * 100
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"vigil/internal/database"
+	"vigil/internal/monitoring"
+)
+
+// GetWebhookDestinations returns the fan-out destinations of a webhook
+func GetWebhookDestinations(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		var destinations []database.WebhookDestination
+		if err := db.Where("webhook_id = ?", webhookID).Order("id").Find(&destinations).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch webhook destinations",
+			})
+		}
+
+		return c.JSON(destinations)
+	}
+}
+
+// CreateWebhookDestination adds a fan-out destination to a webhook
+func CreateWebhookDestination(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		var req struct {
+			Name              string `json:"name" validate:"required"`
+			URL               string `json:"url" validate:"required"`
+			FilterExpression  string `json:"filter_expression"`
+			TransformTemplate string `json:"transform_template"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		destination := database.WebhookDestination{
+			WebhookID:         webhook.ID,
+			Name:              req.Name,
+			URL:               req.URL,
+			FilterExpression:  req.FilterExpression,
+			TransformTemplate: req.TransformTemplate,
+			IsActive:          true,
+		}
+
+		if err := monitoring.ValidateWebhookDestination(&destination); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Create(&destination).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to create webhook destination",
+			})
+		}
+
+		return c.Status(201).JSON(destination)
+	}
+}
+
+// UpdateWebhookDestination updates a fan-out destination
+func UpdateWebhookDestination(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		destinationID, err := strconv.ParseUint(c.Params("destinationId"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid destination ID",
+			})
+		}
+
+		var req struct {
+			Name              string `json:"name" validate:"required"`
+			URL               string `json:"url" validate:"required"`
+			FilterExpression  string `json:"filter_expression"`
+			TransformTemplate string `json:"transform_template"`
+			IsActive          bool   `json:"is_active"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		var destination database.WebhookDestination
+		if err := db.Joins("JOIN webhooks ON webhook_destinations.webhook_id = webhooks.id").
+			Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhook_destinations.id = ? AND webhooks.id = ? AND organizations.owner_id = ?", destinationID, webhookID, userID).
+			First(&destination).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook destination not found",
+			})
+		}
+
+		destination.Name = req.Name
+		destination.URL = req.URL
+		destination.FilterExpression = req.FilterExpression
+		destination.TransformTemplate = req.TransformTemplate
+		destination.IsActive = req.IsActive
+
+		if err := monitoring.ValidateWebhookDestination(&destination); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := db.Save(&destination).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update webhook destination",
+			})
+		}
+
+		return c.JSON(destination)
+	}
+}
+
+// DeleteWebhookDestination removes a fan-out destination. Targets already recorded on
+// deliveries are kept for history.
+func DeleteWebhookDestination(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		destinationID, err := strconv.ParseUint(c.Params("destinationId"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid destination ID",
+			})
+		}
+
+		var destination database.WebhookDestination
+		if err := db.Joins("JOIN webhooks ON webhook_destinations.webhook_id = webhooks.id").
+			Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhook_destinations.id = ? AND webhooks.id = ? AND organizations.owner_id = ?", destinationID, webhookID, userID).
+			First(&destination).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook destination not found",
+			})
+		}
+
+		if err := db.Delete(&destination).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete webhook destination",
+			})
+		}
+
+		return c.SendStatus(204)
+	}
+}
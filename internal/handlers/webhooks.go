@@ -1,14 +1,49 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 
 	"vigil/internal/database"
+	"vigil/internal/monitoring"
 )
 
+// Delivery listing limits: page size, rows read per query and rows read per request
+const (
+	maxDeliveryPageSize   = 200
+	deliveryScanBatchSize = 500
+	maxDeliveryScan       = 5000
+)
+
+// maxWebhookPayloadBytes is the largest per-webhook payload limit, matching the server's body limit
+const maxWebhookPayloadBytes = fiber.DefaultBodyLimit
+
+// Defaults for webhook settings where 0 is meaningful, so they are applied here rather
+// than as column defaults, which GORM would use in place of an explicit 0
+const (
+	defaultWebhookRateLimit               = 600     // requests per minute
+	defaultWebhookPayloadBytes            = 1 << 20 // 1 MiB
+	defaultDeadLetterAlertThreshold       = 10      // dead letters before alerting
+	defaultInvalidPayloadAlertPercent     = 20      // percent of payloads invalid per 10 minutes
+	defaultInvalidSignatureAlertThreshold = 5       // invalid signatures per 10 minutes
+)
+
+// intOrDefault returns the value of an optional request field, or fallback when it was omitted
+func intOrDefault(value *int, fallback int) int {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
 // GetWebhooks returns all webhooks for the current user's organizations
 func GetWebhooks(db *database.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -39,6 +74,38 @@ func CreateWebhook(db *database.DB) fiber.Handler {
 			Secret         string `json:"secret"`
 			RetryCount     int    `json:"retry_count" validate:"min=0,max=10"`
 			TimeoutSeconds int    `json:"timeout_seconds" validate:"min=5,max=300"`
+			ForwardHeaders string `json:"forward_headers"`
+
+			RateLimitPerMinute *int   `json:"rate_limit_per_minute" validate:"min=0"`
+			MaxPayloadBytes    *int   `json:"max_payload_bytes" validate:"min=0"`
+			AllowedSourceCIDRs string `json:"allowed_source_cidrs"`
+
+			DeadLetterAlertThreshold *int `json:"dead_letter_alert_threshold" validate:"min=0"`
+
+			MockEnabled           bool   `json:"mock_enabled"`
+			MockStatusCode        int    `json:"mock_status_code"`
+			MockHeaders           string `json:"mock_headers"`
+			MockBody              string `json:"mock_body"`
+			MockDelayMs           int    `json:"mock_delay_ms" validate:"min=0,max=25000"`
+			MockFailureRate       int    `json:"mock_failure_rate" validate:"min=0,max=100"`
+			MockFailureStatusCode int    `json:"mock_failure_status_code"`
+
+			JSONSchema                 string `json:"json_schema"`
+			RejectInvalidPayloads      bool   `json:"reject_invalid_payloads"`
+			InvalidPayloadAlertPercent *int   `json:"invalid_payload_alert_percent" validate:"min=0,max=100"`
+
+			ExpectedCadenceMinutes int    `json:"expected_cadence_minutes" validate:"min=0"`
+			BusinessHoursOnly      bool   `json:"business_hours_only"`
+			BusinessHoursStart     string `json:"business_hours_start"`
+			BusinessHoursEnd       string `json:"business_hours_end"`
+			BusinessDays           string `json:"business_days"`
+			Timezone               string `json:"timezone"`
+
+			VerificationScheme             string `json:"verification_scheme" validate:"omitempty,oneof=none hmac_sha256 github stripe slack"`
+			SignatureHeader                string `json:"signature_header"`
+			RejectInvalidSignatures        bool   `json:"reject_invalid_signatures"`
+			SignatureToleranceSeconds      int    `json:"signature_tolerance_seconds"`
+			InvalidSignatureAlertThreshold *int   `json:"invalid_signature_alert_threshold"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -47,6 +114,12 @@ func CreateWebhook(db *database.DB) fiber.Handler {
 			})
 		}
 
+		if !monitoring.ValidSignatureScheme(req.VerificationScheme) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid verification scheme",
+			})
+		}
+
 		// Verify user owns the organization
 		var organization database.Organization
 		if err := db.Where("id = ? AND owner_id = ?", req.OrganizationID, userID).First(&organization).Error; err != nil {
@@ -62,7 +135,71 @@ func CreateWebhook(db *database.DB) fiber.Handler {
 			Secret:         req.Secret,
 			RetryCount:     req.RetryCount,
 			TimeoutSeconds: req.TimeoutSeconds,
+			ForwardHeaders: req.ForwardHeaders,
 			IsActive:       true,
+
+			RateLimitPerMinute: intOrDefault(req.RateLimitPerMinute, defaultWebhookRateLimit),
+			MaxPayloadBytes:    intOrDefault(req.MaxPayloadBytes, defaultWebhookPayloadBytes),
+			AllowedSourceCIDRs: req.AllowedSourceCIDRs,
+
+			DeadLetterAlertThreshold: intOrDefault(req.DeadLetterAlertThreshold, defaultDeadLetterAlertThreshold),
+
+			MockEnabled:           req.MockEnabled,
+			MockStatusCode:        req.MockStatusCode,
+			MockHeaders:           req.MockHeaders,
+			MockBody:              req.MockBody,
+			MockDelayMs:           req.MockDelayMs,
+			MockFailureRate:       req.MockFailureRate,
+			MockFailureStatusCode: req.MockFailureStatusCode,
+
+			JSONSchema:                 req.JSONSchema,
+			RejectInvalidPayloads:      req.RejectInvalidPayloads,
+			InvalidPayloadAlertPercent: intOrDefault(req.InvalidPayloadAlertPercent, defaultInvalidPayloadAlertPercent),
+
+			ExpectedCadenceMinutes: req.ExpectedCadenceMinutes,
+			BusinessHoursOnly:      req.BusinessHoursOnly,
+			BusinessHoursStart:     req.BusinessHoursStart,
+			BusinessHoursEnd:       req.BusinessHoursEnd,
+			BusinessDays:           req.BusinessDays,
+			Timezone:               req.Timezone,
+
+			VerificationScheme:             req.VerificationScheme,
+			SignatureHeader:                req.SignatureHeader,
+			RejectInvalidSignatures:        req.RejectInvalidSignatures,
+			SignatureToleranceSeconds:      req.SignatureToleranceSeconds,
+			InvalidSignatureAlertThreshold: intOrDefault(req.InvalidSignatureAlertThreshold, defaultInvalidSignatureAlertThreshold),
+		}
+
+		if _, err := monitoring.ParseSourceCIDRs(webhook.AllowedSourceCIDRs); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if webhook.RateLimitPerMinute < 0 || webhook.MaxPayloadBytes < 0 || webhook.MaxPayloadBytes > maxWebhookPayloadBytes {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rate limit or maximum payload size",
+			})
+		}
+
+		if err := monitoring.ValidateWebhookCadence(&webhook); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := monitoring.ValidateWebhookMock(&webhook); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if strings.TrimSpace(webhook.JSONSchema) != "" {
+			if _, err := monitoring.CompileWebhookSchema(webhook.JSONSchema); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
 		}
 
 		if err := db.Create(&webhook).Error; err != nil {
@@ -116,7 +253,39 @@ func UpdateWebhook(db *database.DB) fiber.Handler {
 			Secret         string `json:"secret"`
 			RetryCount     int    `json:"retry_count" validate:"min=0,max=10"`
 			TimeoutSeconds int    `json:"timeout_seconds" validate:"min=5,max=300"`
+			ForwardHeaders string `json:"forward_headers"`
 			IsActive       bool   `json:"is_active"`
+
+			RateLimitPerMinute *int   `json:"rate_limit_per_minute" validate:"min=0"`
+			MaxPayloadBytes    *int   `json:"max_payload_bytes" validate:"min=0"`
+			AllowedSourceCIDRs string `json:"allowed_source_cidrs"`
+
+			DeadLetterAlertThreshold *int `json:"dead_letter_alert_threshold" validate:"min=0"`
+
+			MockEnabled           bool   `json:"mock_enabled"`
+			MockStatusCode        int    `json:"mock_status_code"`
+			MockHeaders           string `json:"mock_headers"`
+			MockBody              string `json:"mock_body"`
+			MockDelayMs           int    `json:"mock_delay_ms" validate:"min=0,max=25000"`
+			MockFailureRate       int    `json:"mock_failure_rate" validate:"min=0,max=100"`
+			MockFailureStatusCode int    `json:"mock_failure_status_code"`
+
+			JSONSchema                 string `json:"json_schema"`
+			RejectInvalidPayloads      bool   `json:"reject_invalid_payloads"`
+			InvalidPayloadAlertPercent *int   `json:"invalid_payload_alert_percent" validate:"min=0,max=100"`
+
+			ExpectedCadenceMinutes int    `json:"expected_cadence_minutes" validate:"min=0"`
+			BusinessHoursOnly      bool   `json:"business_hours_only"`
+			BusinessHoursStart     string `json:"business_hours_start"`
+			BusinessHoursEnd       string `json:"business_hours_end"`
+			BusinessDays           string `json:"business_days"`
+			Timezone               string `json:"timezone"`
+
+			VerificationScheme             string `json:"verification_scheme" validate:"omitempty,oneof=none hmac_sha256 github stripe slack"`
+			SignatureHeader                string `json:"signature_header"`
+			RejectInvalidSignatures        bool   `json:"reject_invalid_signatures"`
+			SignatureToleranceSeconds      int    `json:"signature_tolerance_seconds"`
+			InvalidSignatureAlertThreshold *int   `json:"invalid_signature_alert_threshold"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -125,6 +294,12 @@ func UpdateWebhook(db *database.DB) fiber.Handler {
 			})
 		}
 
+		if !monitoring.ValidSignatureScheme(req.VerificationScheme) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid verification scheme",
+			})
+		}
+
 		var webhook database.Webhook
 		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
 			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
@@ -139,7 +314,75 @@ func UpdateWebhook(db *database.DB) fiber.Handler {
 		webhook.Secret = req.Secret
 		webhook.RetryCount = req.RetryCount
 		webhook.TimeoutSeconds = req.TimeoutSeconds
+		webhook.ForwardHeaders = req.ForwardHeaders
 		webhook.IsActive = req.IsActive
+		if req.RateLimitPerMinute != nil {
+			webhook.RateLimitPerMinute = *req.RateLimitPerMinute
+		}
+		if req.MaxPayloadBytes != nil {
+			webhook.MaxPayloadBytes = *req.MaxPayloadBytes
+		}
+		webhook.AllowedSourceCIDRs = req.AllowedSourceCIDRs
+		if req.DeadLetterAlertThreshold != nil {
+			webhook.DeadLetterAlertThreshold = *req.DeadLetterAlertThreshold
+		}
+		webhook.MockEnabled = req.MockEnabled
+		webhook.MockStatusCode = req.MockStatusCode
+		webhook.MockHeaders = req.MockHeaders
+		webhook.MockBody = req.MockBody
+		webhook.MockDelayMs = req.MockDelayMs
+		webhook.MockFailureRate = req.MockFailureRate
+		webhook.MockFailureStatusCode = req.MockFailureStatusCode
+		webhook.JSONSchema = req.JSONSchema
+		webhook.RejectInvalidPayloads = req.RejectInvalidPayloads
+		if req.InvalidPayloadAlertPercent != nil {
+			webhook.InvalidPayloadAlertPercent = *req.InvalidPayloadAlertPercent
+		}
+		webhook.ExpectedCadenceMinutes = req.ExpectedCadenceMinutes
+		webhook.BusinessHoursOnly = req.BusinessHoursOnly
+		webhook.BusinessHoursStart = req.BusinessHoursStart
+		webhook.BusinessHoursEnd = req.BusinessHoursEnd
+		webhook.BusinessDays = req.BusinessDays
+		webhook.Timezone = req.Timezone
+		webhook.VerificationScheme = req.VerificationScheme
+		webhook.SignatureHeader = req.SignatureHeader
+		webhook.RejectInvalidSignatures = req.RejectInvalidSignatures
+		webhook.SignatureToleranceSeconds = req.SignatureToleranceSeconds
+		if req.InvalidSignatureAlertThreshold != nil {
+			webhook.InvalidSignatureAlertThreshold = *req.InvalidSignatureAlertThreshold
+		}
+
+		if _, err := monitoring.ParseSourceCIDRs(webhook.AllowedSourceCIDRs); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if webhook.RateLimitPerMinute < 0 || webhook.MaxPayloadBytes < 0 || webhook.MaxPayloadBytes > maxWebhookPayloadBytes {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rate limit or maximum payload size",
+			})
+		}
+
+		if err := monitoring.ValidateWebhookCadence(&webhook); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := monitoring.ValidateWebhookMock(&webhook); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if strings.TrimSpace(webhook.JSONSchema) != "" {
+			if _, err := monitoring.CompileWebhookSchema(webhook.JSONSchema); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
 
 		if err := db.Save(&webhook).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{
@@ -171,6 +414,12 @@ func DeleteWebhook(db *database.DB) fiber.Handler {
 			})
 		}
 
+		if err := db.Where("webhook_id = ?", webhook.ID).Delete(&database.WebhookDestination{}).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to delete webhook destinations",
+			})
+		}
+
 		if err := db.Delete(&webhook).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": "Failed to delete webhook",
@@ -181,8 +430,382 @@ func DeleteWebhook(db *database.DB) fiber.Handler {
 	}
 }
 
-// GetWebhookDeliveries returns delivery history for a webhook
+// GetWebhookDeliveries returns delivery history for a webhook, newest first. Deliveries
+// can be filtered by status, time range, response code and a payload predicate (q), and
+// are paginated with the returned next_cursor.
 func GetWebhookDeliveries(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		limit := c.QueryInt("limit", 50)
+		if limit < 1 || limit > maxDeliveryPageSize {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("limit must be between 1 and %d", maxDeliveryPageSize),
+			})
+		}
+
+		query := db.Model(&database.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+		if value := c.Query("cursor"); value != "" {
+			cursor, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid cursor",
+				})
+			}
+			query = query.Where("id < ?", cursor)
+		}
+
+		if value := c.Query("status"); value != "" {
+			query = query.Where("status IN ?", strings.Split(value, ","))
+		}
+
+		if value := c.Query("from"); value != "" {
+			from, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "from must be an RFC 3339 timestamp",
+				})
+			}
+			query = query.Where("delivered_at >= ?", from)
+		}
+
+		if value := c.Query("to"); value != "" {
+			to, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "to must be an RFC 3339 timestamp",
+				})
+			}
+			query = query.Where("delivered_at < ?", to)
+		}
+
+		if value := c.Query("response_code"); value != "" {
+			code, err := strconv.Atoi(value)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid response code",
+				})
+			}
+			query = query.Where("response_code = ?", code)
+		}
+
+		filter, err := monitoring.ParseFilter(c.Query("q"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var ids []uint
+		var nextCursor *uint
+		if strings.TrimSpace(c.Query("q")) == "" {
+			ids, nextCursor, err = deliveryPage(query, limit)
+		} else {
+			ids, nextCursor, err = filteredDeliveryPage(query, filter, limit)
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch webhook deliveries",
+			})
+		}
+
+		deliveries := []database.WebhookDelivery{}
+		if len(ids) > 0 {
+			if err := db.Preload("Attempts", func(tx *gorm.DB) *gorm.DB {
+				return tx.Order("attempted_at")
+			}).
+				Preload("Targets").
+				Where("id IN ?", ids).
+				Order("id DESC").
+				Find(&deliveries).Error; err != nil {
+				return c.Status(500).JSON(fiber.Map{
+					"error": "Failed to fetch webhook deliveries",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"deliveries":  deliveries,
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+// deliveryPage returns up to limit delivery IDs, newest first, and the cursor of the next
+// page. One extra row is fetched to tell whether a next page exists.
+func deliveryPage(query *gorm.DB, limit int) ([]uint, *uint, error) {
+	var ids []uint
+	if err := query.Order("id DESC").Limit(limit+1).Pluck("id", &ids).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(ids) <= limit {
+		return ids, nil, nil
+	}
+	ids = ids[:limit]
+	return ids, &ids[limit-1], nil
+}
+
+// filteredDeliveryPage returns up to limit IDs of deliveries matching a q filter, newest
+// first, and the cursor of the next page. Payload predicates are evaluated here rather than
+// in SQL since payloads are not always JSON. A page stops early once the scan budget is
+// spent; the cursor lets the caller continue where it left off.
+func filteredDeliveryPage(query *gorm.DB, filter *monitoring.Filter, limit int) ([]uint, *uint, error) {
+	var ids []uint
+	var lastScanned uint
+	scanned := 0
+	for {
+		var batch []database.WebhookDelivery
+		if err := query.Session(&gorm.Session{}).
+			Select("id", "payload", "request_headers").
+			Order("id DESC").
+			Limit(deliveryScanBatchSize).
+			Find(&batch).Error; err != nil {
+			return nil, nil, err
+		}
+
+		for i := range batch {
+			scanned++
+			lastScanned = batch[i].ID
+			if payload, headers := monitoring.DecodeDelivery(&batch[i]); filter.Match(payload, headers) {
+				ids = append(ids, batch[i].ID)
+			}
+
+			// A match past the limit means there is another page
+			if len(ids) > limit {
+				ids = ids[:limit]
+				return ids, &ids[limit-1], nil
+			}
+
+			if scanned == maxDeliveryScan {
+				var more []uint
+				if err := query.Session(&gorm.Session{}).Where("id < ?", lastScanned).Limit(1).Pluck("id", &more).Error; err != nil {
+					return nil, nil, err
+				}
+				if len(more) == 0 {
+					return ids, nil, nil
+				}
+				return ids, &lastScanned, nil
+			}
+		}
+
+		if len(batch) < deliveryScanBatchSize {
+			return ids, nil, nil
+		}
+		query = query.Where("id < ?", lastScanned)
+	}
+}
+
+// GetWebhookDelivery returns a single delivery with its attempt log
+func GetWebhookDelivery(db *database.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		deliveryID, err := strconv.ParseUint(c.Params("deliveryId"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid delivery ID",
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		var delivery database.WebhookDelivery
+		if err := db.Preload("Attempts", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("attempted_at")
+		}).
+			Preload("Targets").
+			Where("id = ? AND webhook_id = ?", deliveryID, webhookID).
+			First(&delivery).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook delivery not found",
+			})
+		}
+
+		return c.JSON(delivery)
+	}
+}
+
+// ReplayWebhookDelivery resends a single delivery, optionally to an alternate URL
+func ReplayWebhookDelivery(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		deliveryID, err := strconv.ParseUint(c.Params("deliveryId"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid delivery ID",
+			})
+		}
+
+		var req struct {
+			URL string `json:"url"`
+		}
+
+		// The body is optional
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+		}
+
+		if err := monitoring.ValidateReplayURL(req.URL); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		var delivery database.WebhookDelivery
+		if err := db.Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&delivery).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook delivery not found",
+			})
+		}
+
+		if err := monitorService.ReplayWebhookDelivery(&delivery, req.URL); err != nil {
+			if err == monitoring.ErrDeliveryInFlight {
+				return c.Status(409).JSON(fiber.Map{
+					"error": "Webhook delivery is still being forwarded",
+				})
+			}
+			if errors.Is(err, monitoring.ErrDeliveryNotReplayable) {
+				return c.Status(422).JSON(fiber.Map{
+					"error": "Only forwarded, failed or captured webhook deliveries can be replayed",
+				})
+			}
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to replay webhook delivery",
+			})
+		}
+
+		return c.Status(202).JSON(fiber.Map{
+			"message":     "Webhook delivery queued for replay",
+			"delivery_id": delivery.ID,
+		})
+	}
+}
+
+// ReplayWebhookDeliveries resends all failed deliveries of a webhook in a time range
+func ReplayWebhookDeliveries(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		var req struct {
+			From time.Time  `json:"from" validate:"required"`
+			To   *time.Time `json:"to"`
+			URL  string     `json:"url"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if req.From.IsZero() {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "from is required",
+			})
+		}
+
+		to := time.Now()
+		if req.To != nil {
+			to = *req.To
+		}
+
+		if !to.After(req.From) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "to must be after from",
+			})
+		}
+
+		if err := monitoring.ValidateReplayURL(req.URL); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		queued, err := monitorService.ReplayFailedWebhookDeliveries(webhook.ID, req.From, to, req.URL)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to replay webhook deliveries",
+			})
+		}
+
+		return c.Status(202).JSON(fiber.Map{
+			"message": "Failed webhook deliveries queued for replay",
+			"queued":  queued,
+		})
+	}
+}
+
+// GetWebhookDeadLetters returns the dead-letter queue of a webhook
+func GetWebhookDeadLetters(db *database.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID := c.Locals("user_id").(uint)
 		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
@@ -203,22 +826,39 @@ func GetWebhookDeliveries(db *database.DB) fiber.Handler {
 		}
 
 		var deliveries []database.WebhookDelivery
-		if err := db.Where("webhook_id = ?", webhookID).
-			Order("delivered_at DESC").
+		if err := db.Preload("Attempts", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("attempted_at")
+		}).
+			Where("webhook_id = ? AND status = ?", webhookID, "dead_letter").
+			Order("dead_lettered_at DESC").
 			Limit(100).
 			Find(&deliveries).Error; err != nil {
 			return c.Status(500).JSON(fiber.Map{
-				"error": "Failed to fetch webhook deliveries",
+				"error": "Failed to fetch dead-lettered deliveries",
 			})
 		}
 
-		return c.JSON(deliveries)
+		var depth int64
+		if err := db.Model(&database.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", webhookID, "dead_letter").
+			Count(&depth).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to count dead-lettered deliveries",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"depth":      depth,
+			"deliveries": deliveries,
+		})
 	}
 }
 
-// ReceiveWebhook handles incoming webhook deliveries
-func ReceiveWebhook(db *database.DB) fiber.Handler {
+// PurgeWebhookDeadLetters deletes dead-lettered deliveries of a webhook, either the
+// listed ones or the whole queue
+func PurgeWebhookDeadLetters(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
 		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
 		if err != nil {
 			return c.Status(400).JSON(fiber.Map{
@@ -226,23 +866,265 @@ func ReceiveWebhook(db *database.DB) fiber.Handler {
 			})
 		}
 
+		var req struct {
+			DeliveryIDs []uint `json:"delivery_ids"`
+		}
+
+		// The body is optional
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+		}
+
+		// Verify user has access to this webhook
 		var webhook database.Webhook
-		if err := db.Where("id = ? AND is_active = ?", webhookID, true).First(&webhook).Error; err != nil {
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
 			return c.Status(404).JSON(fiber.Map{
 				"error": "Webhook not found",
 			})
 		}
 
-		// Get the request body
-		payload := string(c.Body())
+		purged, err := monitorService.PurgeDeadLetters(&webhook, req.DeliveryIDs)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to purge dead-lettered deliveries",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Dead-lettered deliveries purged",
+			"purged":  purged,
+		})
+	}
+}
+
+// RedriveWebhookDeadLetters queues dead-lettered deliveries of a webhook to be forwarded
+// again, either the listed ones or the whole queue
+func RedriveWebhookDeadLetters(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		var req struct {
+			DeliveryIDs []uint `json:"delivery_ids"`
+			URL         string `json:"url"`
+		}
+
+		// The body is optional
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+		}
+
+		if err := monitoring.ValidateReplayURL(req.URL); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		queued, err := monitorService.RedriveDeadLetters(&webhook, req.DeliveryIDs, req.URL)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to redrive dead-lettered deliveries",
+			})
+		}
+
+		return c.Status(202).JSON(fiber.Map{
+			"message": "Dead-lettered deliveries queued for redrive",
+			"queued":  queued,
+		})
+	}
+}
+
+// RotateWebhookToken issues a new ingest token for a webhook. The old token keeps
+// working for grace_period_minutes, 60 by default.
+func RotateWebhookToken(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		webhookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid webhook ID",
+			})
+		}
+
+		req := struct {
+			GracePeriodMinutes int `json:"grace_period_minutes" validate:"min=0"`
+		}{GracePeriodMinutes: 60}
+
+		// The body is optional
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+		}
+
+		// Verify user has access to this webhook
+		var webhook database.Webhook
+		if err := db.Joins("JOIN organizations ON webhooks.organization_id = organizations.id").
+			Where("webhooks.id = ? AND organizations.owner_id = ?", webhookID, userID).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		if err := monitorService.RotateIngestToken(&webhook, time.Duration(req.GracePeriodMinutes)*time.Minute); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(webhook)
+	}
+}
+
+// ReceiveWebhook handles incoming webhook deliveries
+func ReceiveWebhook(db *database.DB, monitorService *monitoring.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Params("token")
+
+		// Match the current ingest token, or the previous one during its grace period
+		var webhook database.Webhook
+		if err := db.Where("is_active = ? AND (ingest_token = ? OR (previous_ingest_token = ? AND previous_ingest_token_expires_at > ?))",
+			true, token, token, time.Now()).
+			First(&webhook).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Webhook not found",
+			})
+		}
+
+		if !monitoring.WebhookSourceAllowed(&webhook, c.IP()) {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Source address not allowed",
+			})
+		}
+
+		if allowed, retryAfter := monitorService.AllowWebhookRequest(&webhook); !allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+			return c.Status(429).JSON(fiber.Map{
+				"error": "Rate limit exceeded",
+			})
+		}
+
+		if webhook.MaxPayloadBytes > 0 && len(c.Body()) > webhook.MaxPayloadBytes {
+			return c.Status(413).JSON(fiber.Map{
+				"error": "Payload too large",
+			})
+		}
+
+		// Only mock endpoints accept methods other than POST
+		if c.Method() != fiber.MethodPost && !webhook.MockEnabled {
+			return c.Status(405).JSON(fiber.Map{
+				"error": "Method not allowed",
+			})
+		}
+
+		// Get the request body and headers
+		body := c.Body()
+		payload := string(body)
+
+		headers := make(map[string]string)
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			name := http.CanonicalHeaderKey(string(key))
+			if existing, ok := headers[name]; ok {
+				headers[name] = existing + ", " + string(value)
+			} else {
+				headers[name] = string(value)
+			}
+		})
+		requestHeaders, _ := json.Marshal(headers)
 
 		// Create webhook delivery record
+		now := time.Now()
 		delivery := database.WebhookDelivery{
-			WebhookID:   uint(webhookID),
-			Payload:     payload,
-			Status:      "pending",
-			DeliveredAt: time.Now(),
-			RetryCount:  0,
+			WebhookID:       webhook.ID,
+			Payload:         payload,
+			RequestHeaders:  string(requestHeaders),
+			Method:          c.Method(),
+			QueryString:     string(c.Request().URI().QueryString()),
+			SourceIP:        c.IP(),
+			Status:          "pending",
+			DeliveredAt:     now,
+			RetryCount:      0,
+			NextAttemptAt:   &now,
+			SignatureStatus: "unverified",
+		}
+
+		// Verify the signature, if the webhook has a scheme configured
+		if webhook.VerificationScheme != "" && webhook.VerificationScheme != monitoring.SignatureSchemeNone {
+			header := func(name string) string { return c.Get(name) }
+			if err := monitoring.VerifyWebhookSignature(&webhook, header, body, time.Now()); err != nil {
+				delivery.SignatureStatus = "invalid"
+				delivery.SignatureError = err.Error()
+				if webhook.RejectInvalidSignatures {
+					delivery.Status = "rejected"
+					delivery.NextAttemptAt = nil
+				}
+				monitorService.RecordInvalidWebhookSignature(&webhook)
+			} else {
+				delivery.SignatureStatus = "valid"
+				monitorService.RecordValidWebhookSignature(&webhook)
+			}
+		}
+
+		// Validate the payload against the webhook's schema, unless it was already rejected
+		var violations []string
+		if delivery.Status != "rejected" && strings.TrimSpace(webhook.JSONSchema) != "" {
+			var err error
+			violations, err = monitoring.ValidateWebhookPayload(&webhook, body)
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{
+					"error": "Failed to validate webhook payload",
+				})
+			}
+
+			if len(violations) > 0 {
+				validationErrors, _ := json.Marshal(violations)
+				delivery.ValidationStatus = "invalid"
+				delivery.ValidationErrors = string(validationErrors)
+				if webhook.RejectInvalidPayloads {
+					delivery.Status = "rejected"
+					delivery.NextAttemptAt = nil
+				}
+			} else {
+				delivery.ValidationStatus = "valid"
+			}
+			monitorService.RecordWebhookPayloadValidation(&webhook, len(violations) == 0)
+		}
+
+		// In mock mode the delivery is captured for inspection rather than forwarded
+		var mock monitoring.MockResponse
+		if webhook.MockEnabled && delivery.Status != "rejected" {
+			mock = monitoring.MockWebhookResponse(&webhook)
+			delivery.Status = "captured"
+			delivery.NextAttemptAt = nil
+			delivery.ResponseCode = mock.StatusCode
 		}
 
 		if err := db.Create(&delivery).Error; err != nil {
@@ -251,8 +1133,35 @@ func ReceiveWebhook(db *database.DB) fiber.Handler {
 			})
 		}
 
-		// TODO: Process webhook delivery asynchronously
-		// This would involve sending the webhook to the target URL and updating the delivery status
+		if delivery.SignatureStatus == "invalid" && delivery.Status == "rejected" {
+			return c.Status(401).JSON(fiber.Map{
+				"error":       "Invalid webhook signature",
+				"delivery_id": delivery.ID,
+			})
+		}
+
+		// The provider is still sending, even if this payload is malformed
+		monitorService.RecordWebhookReceived(&webhook, now)
+
+		if delivery.Status == "rejected" {
+			return c.Status(422).JSON(fiber.Map{
+				"error":             "Webhook payload does not match schema",
+				"delivery_id":       delivery.ID,
+				"validation_errors": violations,
+			})
+		}
+
+		if delivery.Status == "captured" {
+			time.Sleep(mock.Delay)
+			for name, value := range mock.Headers {
+				c.Set(name, value)
+			}
+			return c.Status(mock.StatusCode).SendString(mock.Body)
+		}
+
+		// Forward the delivery to the target URL and any matching destinations asynchronously
+		monitorService.DispatchWebhookDelivery(delivery.ID)
+		monitorService.RouteWebhookDelivery(&webhook, &delivery)
 
 		return c.JSON(fiber.Map{
 			"message":     "Webhook received",
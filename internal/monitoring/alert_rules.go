@@ -0,0 +1,314 @@
+package monitoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// maxRuleChecks bounds how many checks a single rule evaluation reads
+const maxRuleChecks = 1000
+
+// ruleFields maps the check fields rules can test to whether they are numeric
+var ruleFields = map[string]bool{
+	"response_time": true,
+	"status_code":   true,
+	"status":        false,
+	"error_message": false,
+}
+
+// ruleOperators maps rule operators to how they are written in alert messages
+var ruleOperators = map[string]string{
+	"gt":       ">",
+	"gte":      ">=",
+	"lt":       "<",
+	"lte":      "<=",
+	"eq":       "==",
+	"ne":       "!=",
+	"contains": "contains",
+}
+
+// ValidateAlertRule checks an alert rule's condition. The monitor is checked by the caller;
+// a rule must cover a monitor or at least one tag.
+func ValidateAlertRule(rule *database.AlertRule) error {
+	numeric, ok := ruleFields[rule.Field]
+	if !ok {
+		return fmt.Errorf("field must be response_time, status_code, status or error_message")
+	}
+
+	if _, ok := ruleOperators[rule.Operator]; !ok {
+		return fmt.Errorf("operator must be gt, gte, lt, lte, eq, ne or contains")
+	}
+
+	if numeric {
+		if rule.Operator == "contains" {
+			return fmt.Errorf("contains only applies to status and error_message")
+		}
+		if _, err := strconv.ParseFloat(rule.Value, 64); err != nil {
+			return fmt.Errorf("value must be a number for %s", rule.Field)
+		}
+	} else if rule.Operator != "eq" && rule.Operator != "ne" && rule.Operator != "contains" {
+		return fmt.Errorf("%s can only be compared with eq, ne or contains", rule.Field)
+	}
+
+	if rule.Aggregate != "" {
+		if _, ok := aggregatePercentile(rule.Aggregate); !ok && rule.Aggregate != "avg" && rule.Aggregate != "min" && rule.Aggregate != "max" {
+			return fmt.Errorf("aggregate must be p50, p90, p95, p99, avg, min or max")
+		}
+		if !numeric {
+			return fmt.Errorf("aggregates only apply to response_time and status_code")
+		}
+	}
+
+	if rule.WindowMinutes < 0 || rule.WindowMinutes > 24*60 {
+		return fmt.Errorf("window_minutes must be between 0 and 1440")
+	}
+	if rule.WindowChecks < 0 || rule.WindowChecks > 100 {
+		return fmt.Errorf("window_checks must be between 0 and 100")
+	}
+	if rule.WindowMinutes > 0 && rule.WindowChecks > 0 {
+		return fmt.Errorf("set either window_minutes or window_checks, not both")
+	}
+
+	if rule.MinMatches < 1 {
+		return fmt.Errorf("min_matches must be at least 1")
+	}
+	if rule.WindowChecks > 0 && rule.MinMatches > rule.WindowChecks {
+		return fmt.Errorf("min_matches cannot exceed window_checks")
+	}
+
+	if _, ok := severityRanks[rule.Severity]; !ok {
+		return fmt.Errorf("severity must be low, medium, high or critical")
+	}
+
+	if rule.MonitorID == nil && len(ParseTags(rule.Tags)) == 0 {
+		return fmt.Errorf("an alert rule must cover a monitor or at least one tag")
+	}
+
+	return nil
+}
+
+// DescribeAlertRule writes a rule's condition out in one line
+func DescribeAlertRule(rule *database.AlertRule) string {
+	condition := fmt.Sprintf("%s %s %s", rule.Field, ruleOperators[rule.Operator], rule.Value)
+	if rule.Operator == "contains" || !ruleFields[rule.Field] {
+		condition = fmt.Sprintf("%s %s %q", rule.Field, ruleOperators[rule.Operator], rule.Value)
+	}
+
+	switch {
+	case rule.Aggregate != "" && rule.WindowMinutes > 0:
+		return fmt.Sprintf("%s %s over %d minutes", rule.Aggregate, condition, rule.WindowMinutes)
+	case rule.Aggregate != "" && rule.WindowChecks > 0:
+		return fmt.Sprintf("%s %s over the last %d checks", rule.Aggregate, condition, rule.WindowChecks)
+	case rule.Aggregate != "":
+		return condition
+	case rule.WindowMinutes > 0:
+		return fmt.Sprintf("%d checks in %d minutes with %s", rule.MinMatches, rule.WindowMinutes, condition)
+	case rule.WindowChecks > 0:
+		return fmt.Sprintf("%d of the last %d checks with %s", rule.MinMatches, rule.WindowChecks, condition)
+	}
+	return "latest check with " + condition
+}
+
+// ResolveAlertRuleAlerts resolves the open alerts raised by a rule, e.g. after it changes
+func (s *Service) ResolveAlertRuleAlerts(ruleID uint) {
+	s.resolveAlertsWhere("alert_rule_id = ? AND resolved_at IS NULL", ruleID)
+}
+
+// evaluateAlertRules runs the active rules covering a monitor against its recent checks,
+// raising an alert for each rule that holds and resolving alerts of rules that no longer do
+func (s *Service) evaluateAlertRules(monitor *database.Monitor) {
+	var rules []database.AlertRule
+	if err := s.db.Where("organization_id = ? AND is_active = ? AND (monitor_id = ? OR monitor_id IS NULL)",
+		monitor.OrganizationID, true, monitor.ID).
+		Order("id").
+		Find(&rules).Error; err != nil {
+		s.log.Errorf("Failed to load alert rules for monitor %d: %v", monitor.ID, err)
+		return
+	}
+
+	monitorTags := make(map[string]bool)
+	for _, tag := range ParseTags(monitor.Tags) {
+		monitorTags[tag] = true
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.MonitorID == nil && !tagsOverlap(ParseTags(rule.Tags), monitorTags) {
+			continue
+		}
+
+		holds, observed, err := s.alertRuleHolds(rule, monitor.ID)
+		if err != nil {
+			s.log.Errorf("Failed to evaluate alert rule %d on monitor %d: %v", rule.ID, monitor.ID, err)
+			continue
+		}
+
+		if !holds {
+			s.resolveAlertsWhere("monitor_id = ? AND alert_rule_id = ? AND resolved_at IS NULL", monitor.ID, rule.ID)
+			continue
+		}
+
+		ruleID := rule.ID
+		monitorID := monitor.ID
+		s.raiseAlert(&database.Alert{
+			MonitorID:          &monitorID,
+			AlertRuleID:        &ruleID,
+			Type:               "rule",
+			Message:            fmt.Sprintf("Monitor %s matched alert rule %s (%s): %s", monitor.Name, rule.Name, DescribeAlertRule(rule), observed),
+			Severity:           rule.Severity,
+			EscalationPolicyID: monitor.EscalationPolicyID,
+		}, monitor.OrganizationID)
+	}
+}
+
+// alertRuleHolds reports whether a rule's condition holds on a monitor's recent checks, with
+// a short description of what was observed
+func (s *Service) alertRuleHolds(rule *database.AlertRule, monitorID uint) (bool, string, error) {
+	query := s.db.Select("id", "status", "response_time", "status_code", "error_message", "checked_at").
+		Where("monitor_id = ?", monitorID).
+		Order("checked_at DESC")
+	switch {
+	case rule.WindowMinutes > 0:
+		query = query.Where("checked_at >= ?", time.Now().Add(-time.Duration(rule.WindowMinutes)*time.Minute)).Limit(maxRuleChecks)
+	case rule.WindowChecks > 0:
+		query = query.Limit(rule.WindowChecks)
+	default:
+		query = query.Limit(1)
+	}
+
+	var checks []database.MonitorCheck
+	if err := query.Find(&checks).Error; err != nil {
+		return false, "", err
+	}
+	if len(checks) == 0 {
+		return false, "", nil
+	}
+
+	if rule.Aggregate != "" {
+		values := make([]float64, 0, len(checks))
+		for _, check := range checks {
+			values = append(values, checkNumber(&check, rule.Field))
+		}
+		value := aggregate(values, rule.Aggregate)
+		threshold, _ := strconv.ParseFloat(rule.Value, 64)
+		observed := fmt.Sprintf("%s %s was %s across %d checks", rule.Aggregate, rule.Field, strconv.FormatFloat(value, 'f', -1, 64), len(checks))
+		return compareNumber(value, rule.Operator, threshold), observed, nil
+	}
+
+	matches := 0
+	for _, check := range checks {
+		if checkMatches(&check, rule) {
+			matches++
+		}
+	}
+	observed := fmt.Sprintf("%d of %d checks matched", matches, len(checks))
+	return matches >= rule.MinMatches, observed, nil
+}
+
+// checkMatches compares a single check against a rule's condition
+func checkMatches(check *database.MonitorCheck, rule *database.AlertRule) bool {
+	if ruleFields[rule.Field] {
+		threshold, _ := strconv.ParseFloat(rule.Value, 64)
+		return compareNumber(checkNumber(check, rule.Field), rule.Operator, threshold)
+	}
+
+	text := check.Status
+	if rule.Field == "error_message" {
+		text = check.ErrorMessage
+	}
+
+	switch rule.Operator {
+	case "eq":
+		return strings.EqualFold(text, rule.Value)
+	case "ne":
+		return !strings.EqualFold(text, rule.Value)
+	case "contains":
+		return strings.Contains(strings.ToLower(text), strings.ToLower(rule.Value))
+	}
+	return false
+}
+
+// checkNumber returns a numeric field of a check
+func checkNumber(check *database.MonitorCheck, field string) float64 {
+	if field == "status_code" {
+		return float64(check.StatusCode)
+	}
+	return float64(check.ResponseTime)
+}
+
+// compareNumber applies a numeric rule operator
+func compareNumber(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "gt":
+		return value > threshold
+	case "gte":
+		return value >= threshold
+	case "lt":
+		return value < threshold
+	case "lte":
+		return value <= threshold
+	case "eq":
+		return value == threshold
+	case "ne":
+		return value != threshold
+	}
+	return false
+}
+
+// aggregate reduces values with avg, min, max or a percentile such as p95
+func aggregate(values []float64, name string) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	switch name {
+	case "min":
+		return sorted[0]
+	case "max":
+		return sorted[len(sorted)-1]
+	case "avg":
+		total := 0.0
+		for _, value := range sorted {
+			total += value
+		}
+		return total / float64(len(sorted))
+	}
+
+	// Nearest-rank percentile
+	percentile, _ := aggregatePercentile(name)
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// aggregatePercentile parses a supported percentile aggregate such as p95
+func aggregatePercentile(name string) (float64, bool) {
+	switch name {
+	case "p50":
+		return 50, true
+	case "p90":
+		return 90, true
+	case "p95":
+		return 95, true
+	case "p99":
+		return 99, true
+	}
+	return 0, false
+}
+
+// tagsOverlap reports whether any of tags is in set
+func tagsOverlap(tags []string, set map[string]bool) bool {
+	for _, tag := range tags {
+		if set[tag] {
+			return true
+		}
+	}
+	return false
+}
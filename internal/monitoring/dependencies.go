@@ -0,0 +1,158 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+)
+
+// DependencyNode is a monitor in the dependency tree with its latest state
+type DependencyNode struct {
+	MonitorID uint              `json:"monitor_id"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"` // unknown until the monitor has been checked
+	Reason    string            `json:"reason,omitempty"`
+	CheckedAt *time.Time        `json:"checked_at"`
+	Children  []*DependencyNode `json:"children"`
+}
+
+// ValidateMonitorDependencies checks that giving a monitor the parents parentIDs, alongside the
+// existing dependencies, keeps the dependency graph free of cycles
+func ValidateMonitorDependencies(dependencies []database.MonitorDependency, monitorID uint, parentIDs []uint) error {
+	parents := make(map[uint][]uint)
+	for _, dependency := range dependencies {
+		if dependency.MonitorID != monitorID {
+			parents[dependency.MonitorID] = append(parents[dependency.MonitorID], dependency.ParentID)
+		}
+	}
+
+	// Walk up from the new parents; reaching the monitor again means it would depend on itself
+	visited := make(map[uint]bool)
+	pending := append([]uint(nil), parentIDs...)
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if id == monitorID {
+			return fmt.Errorf("monitor %d cannot depend on itself, directly or through its parents", monitorID)
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		pending = append(pending, parents[id]...)
+	}
+
+	return nil
+}
+
+// DependencyTree arranges monitors by their dependencies, with monitors that have no parent
+// among them at the top. A monitor with several parents appears under each of them.
+func (s *Service) DependencyTree(monitors []database.Monitor) ([]*DependencyNode, error) {
+	ids := make([]uint, 0, len(monitors))
+	for _, monitor := range monitors {
+		ids = append(ids, monitor.ID)
+	}
+
+	var dependencies []database.MonitorDependency
+	if err := s.db.Where("monitor_id IN ? AND parent_id IN ?", ids, ids).Find(&dependencies).Error; err != nil {
+		return nil, err
+	}
+
+	checks, err := latestChecks(s.db.DB, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	hasParent := make(map[uint]bool)
+	for _, dependency := range dependencies {
+		children[dependency.ParentID] = append(children[dependency.ParentID], dependency.MonitorID)
+		hasParent[dependency.MonitorID] = true
+	}
+
+	names := make(map[uint]string)
+	for _, monitor := range monitors {
+		names[monitor.ID] = monitor.Name
+	}
+
+	var build func(id uint) *DependencyNode
+	build = func(id uint) *DependencyNode {
+		node := &DependencyNode{MonitorID: id, Name: names[id], Status: "unknown", Children: []*DependencyNode{}}
+		if check, ok := checks[id]; ok {
+			checkedAt := check.CheckedAt
+			node.Status = check.Status
+			node.Reason = check.Reason
+			node.CheckedAt = &checkedAt
+		}
+		for _, child := range children[id] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+
+	tree := []*DependencyNode{}
+	for _, monitor := range monitors {
+		if !hasParent[monitor.ID] {
+			tree = append(tree, build(monitor.ID))
+		}
+	}
+	return tree, nil
+}
+
+// downParent returns a parent of a monitor whose latest check is down, if there is one
+func (s *Service) downParent(monitor *database.Monitor) (*database.Monitor, bool) {
+	var parentIDs []uint
+	if err := s.db.Model(&database.MonitorDependency{}).
+		Where("monitor_id = ?", monitor.ID).
+		Pluck("parent_id", &parentIDs).Error; err != nil {
+		s.log.Errorf("Failed to load parents of monitor %d: %v", monitor.ID, err)
+		return nil, false
+	}
+	if len(parentIDs) == 0 {
+		return nil, false
+	}
+
+	checks, err := latestChecks(s.db.DB, parentIDs)
+	if err != nil {
+		s.log.Errorf("Failed to load parent checks of monitor %d: %v", monitor.ID, err)
+		return nil, false
+	}
+
+	for _, id := range parentIDs {
+		if check, ok := checks[id]; ok && check.Status == "down" {
+			var parent database.Monitor
+			if err := s.db.Select("id", "name").First(&parent, id).Error; err != nil {
+				return nil, false
+			}
+			return &parent, true
+		}
+	}
+	return nil, false
+}
+
+// latestChecks returns the most recent check of each monitor, keyed by monitor ID
+func latestChecks(db *gorm.DB, monitorIDs []uint) (map[uint]database.MonitorCheck, error) {
+	latest := make(map[uint]database.MonitorCheck)
+	if len(monitorIDs) == 0 {
+		return latest, nil
+	}
+
+	var checks []database.MonitorCheck
+	if err := db.Select("id", "monitor_id", "status", "reason", "checked_at").
+		Where("id IN (?)", db.Model(&database.MonitorCheck{}).
+			Select("MAX(id)").
+			Where("monitor_id IN ?", monitorIDs).
+			Group("monitor_id")).
+		Find(&checks).Error; err != nil {
+		return nil, err
+	}
+
+	for _, check := range checks {
+		latest[check.MonitorID] = check
+	}
+	return latest, nil
+}
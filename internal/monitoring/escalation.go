@@ -0,0 +1,164 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// escalationSchedule is how often alerts are checked for a due escalation
+const escalationSchedule = "0 * * * * *"
+
+// ValidateEscalationLevels checks the levels of a policy before they are stored. Positions
+// are assigned from the order of the levels.
+func ValidateEscalationLevels(levels []database.EscalationLevel) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("an escalation policy needs at least one level")
+	}
+
+	for i, level := range levels {
+		if level.DelayMinutes < 0 {
+			return fmt.Errorf("level %d: delay_minutes cannot be negative", i+1)
+		}
+		if len(level.Targets) == 0 {
+			return fmt.Errorf("level %d: at least one target is required", i+1)
+		}
+		for _, target := range level.Targets {
+			switch target.Type {
+			case "channel":
+				if target.NotificationChannelID == nil {
+					return fmt.Errorf("level %d: channel targets need a notification_channel_id", i+1)
+				}
+			case "user":
+				if target.UserID == nil {
+					return fmt.Errorf("level %d: user targets need a user_id", i+1)
+				}
+			case "schedule":
+				if target.ScheduleID == nil {
+					return fmt.Errorf("level %d: schedule targets need a schedule_id", i+1)
+				}
+			default:
+				return fmt.Errorf("level %d: unknown target type %q", i+1, target.Type)
+			}
+		}
+	}
+
+	return nil
+}
+
+// processDueEscalations advances unacknowledged alerts whose next escalation is due
+func (s *Service) processDueEscalations() {
+	var alertIDs []uint
+	if err := s.db.Model(&database.Alert{}).
+		Where("escalation_policy_id IS NOT NULL AND resolved_at IS NULL AND acknowledged_at IS NULL AND next_escalation_at <= ?", time.Now()).
+		Order("next_escalation_at").
+		Pluck("id", &alertIDs).Error; err != nil {
+		s.log.Errorf("Failed to load due escalations: %v", err)
+		return
+	}
+
+	for _, alertID := range alertIDs {
+		s.escalateAlert(alertID)
+	}
+}
+
+// escalateAlert notifies the next level of an alert's escalation policy and schedules
+// the one after it. Acknowledged and resolved alerts are left alone.
+func (s *Service) escalateAlert(alertID uint) {
+	var alert database.Alert
+	if err := s.db.First(&alert, alertID).Error; err != nil {
+		s.log.Errorf("Failed to load alert %d: %v", alertID, err)
+		return
+	}
+
+	if alert.EscalationPolicyID == nil || alert.ResolvedAt != nil || alert.AcknowledgedAt != nil {
+		return
+	}
+
+	// Nobody is paged for a monitor under maintenance; carry on once the window ends
+	if alert.MonitorID != nil {
+		var monitor database.Monitor
+		if err := s.db.First(&monitor, *alert.MonitorID).Error; err == nil {
+			if end, inMaintenance := s.maintenanceEnd(&monitor); inMaintenance {
+				s.db.Model(&database.Alert{}).Where("id = ?", alert.ID).Update("next_escalation_at", end)
+				return
+			}
+		}
+	}
+
+	position := alert.EscalationLevel + 1
+	level := s.escalationLevel(*alert.EscalationPolicyID, position)
+
+	updates := map[string]interface{}{
+		"escalation_level":   position,
+		"next_escalation_at": nil,
+	}
+	if level == nil {
+		// The policy ran out of levels, or was changed underneath the alert
+		updates["escalation_level"] = alert.EscalationLevel
+	} else if next := s.escalationLevel(*alert.EscalationPolicyID, position+1); next != nil {
+		updates["next_escalation_at"] = time.Now().Add(time.Duration(next.DelayMinutes) * time.Minute)
+	}
+
+	// Claim this step so overlapping workers don't notify the same level twice
+	result := s.db.Model(&database.Alert{}).
+		Where("id = ? AND escalation_level = ? AND resolved_at IS NULL AND acknowledged_at IS NULL", alert.ID, alert.EscalationLevel).
+		Updates(updates)
+	if result.Error != nil {
+		s.log.Errorf("Failed to escalate alert %d: %v", alert.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 || level == nil {
+		return
+	}
+
+	for i := range level.Targets {
+		s.notifyEscalationTarget(&alert, &level.Targets[i], position, 0)
+	}
+
+	s.recordAlertEvent(alert.ID, "escalated", fmt.Sprintf("Escalated to level %d", position))
+}
+
+// notifyEscalationTarget notifies one target of an escalation level and reports whether a
+// notification was recorded
+func (s *Service) notifyEscalationTarget(alert *database.Alert, target *database.EscalationTarget, position, reminder int) bool {
+	switch target.Type {
+	case "channel":
+		var channel database.NotificationChannel
+		if err := s.db.Where("id = ? AND is_active = ?", *target.NotificationChannelID, true).First(&channel).Error; err != nil {
+			s.log.Warnf("Skipping escalation target %d of alert %d: channel unavailable", target.ID, alert.ID)
+			return false
+		}
+		return s.notifyChannel(alert, &channel, position, reminder)
+
+	case "user":
+		var user database.User
+		if err := s.db.First(&user, *target.UserID).Error; err != nil {
+			s.log.Warnf("Skipping escalation target %d of alert %d: user not found", target.ID, alert.ID)
+			return false
+		}
+		return s.notifyUser(alert, &user, position, reminder)
+
+	case "schedule":
+		user := s.onCallUser(s.alertOrganizationID(alert), *target.ScheduleID)
+		if user == nil {
+			s.log.Warnf("Skipping escalation target %d of alert %d: nobody is on call", target.ID, alert.ID)
+			return false
+		}
+		return s.notifyUser(alert, user, position, reminder)
+	}
+	return false
+}
+
+// escalationLevel loads the level at a position of a policy with its targets, or nil when
+// the policy has no such level
+func (s *Service) escalationLevel(policyID uint, position int) *database.EscalationLevel {
+	var level database.EscalationLevel
+	if err := s.db.Preload("Targets").
+		Where("escalation_policy_id = ? AND position = ?", policyID, position).
+		First(&level).Error; err != nil {
+		return nil
+	}
+	return &level
+}
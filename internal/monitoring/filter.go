@@ -0,0 +1,477 @@
+package monitoring
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed predicate over a webhook delivery's JSON payload and headers, e.g.
+//
+//	payload.type == "invoice.paid" && (payload.amount >= 100 || headers.X-Priority == "high")
+//
+// Paths start with payload or headers, and paths without either prefix are read from the
+// payload; numeric segments index arrays. Supported operators are == (or =), !=, >, >=, <,
+// <=, contains, &&, || and !. A bare path tests that a value exists.
+type Filter struct {
+	source string
+	root   filterNode
+}
+
+// filterNode is a node of a parsed filter expression
+type filterNode interface {
+	eval(env filterEnv) bool
+}
+
+// filterEnv is what a filter is evaluated against
+type filterEnv struct {
+	payload interface{}
+	headers map[string]string
+}
+
+// filterAnd matches when both sides match
+type filterAnd struct{ left, right filterNode }
+
+// filterOr matches when either side matches
+type filterOr struct{ left, right filterNode }
+
+// filterNot inverts its operand
+type filterNot struct{ operand filterNode }
+
+// filterExists matches when the path resolves to a non-null value
+type filterExists struct{ path filterPath }
+
+// filterCompare compares the value at a path with a literal
+type filterCompare struct {
+	path  filterPath
+	op    string
+	value interface{} // string, float64, bool or nil
+}
+
+// filterPath is a path into the payload or headers
+type filterPath struct {
+	source   string // "payload" or "headers"
+	segments []string
+}
+
+// ParseFilter parses a filter expression. An empty expression matches everything.
+func ParseFilter(expression string) (*Filter, error) {
+	if strings.TrimSpace(expression) == "" {
+		return &Filter{}, nil
+	}
+
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+
+	return &Filter{source: expression, root: root}, nil
+}
+
+// Match reports whether a payload and its headers satisfy the filter
+func (f *Filter) Match(payload interface{}, headers map[string]string) bool {
+	if f.root == nil {
+		return true
+	}
+	return f.root.eval(filterEnv{payload: payload, headers: headers})
+}
+
+// String returns the expression the filter was parsed from
+func (f *Filter) String() string {
+	return f.source
+}
+
+func (n filterAnd) eval(env filterEnv) bool { return n.left.eval(env) && n.right.eval(env) }
+
+func (n filterOr) eval(env filterEnv) bool { return n.left.eval(env) || n.right.eval(env) }
+
+func (n filterNot) eval(env filterEnv) bool { return !n.operand.eval(env) }
+
+func (n filterExists) eval(env filterEnv) bool {
+	value, ok := n.path.resolve(env)
+	return ok && value != nil
+}
+
+func (n filterCompare) eval(env filterEnv) bool {
+	actual, ok := n.path.resolve(env)
+	if !ok {
+		// Missing values only satisfy != and == null
+		return (n.op == "==" && n.value == nil) || (n.op == "!=" && n.value != nil)
+	}
+
+	switch n.op {
+	case "==":
+		return filterEqual(actual, n.value)
+	case "!=":
+		return !filterEqual(actual, n.value)
+	case "contains":
+		return filterContains(actual, n.value)
+	default:
+		cmp, ok := filterOrder(actual, n.value)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case ">":
+			return cmp > 0
+		case ">=":
+			return cmp >= 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		}
+	}
+	return false
+}
+
+// resolve looks up the path, reporting whether it exists
+func (p filterPath) resolve(env filterEnv) (interface{}, bool) {
+	if p.source == "headers" {
+		if len(p.segments) != 1 {
+			return nil, false
+		}
+		value, ok := env.headers[http.CanonicalHeaderKey(p.segments[0])]
+		return value, ok
+	}
+
+	current := env.payload
+	for _, segment := range p.segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// filterEqual compares a payload value with a literal
+func filterEqual(actual, expected interface{}) bool {
+	switch want := expected.(type) {
+	case nil:
+		return actual == nil
+	case string:
+		got, ok := actual.(string)
+		return ok && got == want
+	case float64:
+		got, ok := filterNumber(actual)
+		return ok && got == want
+	case bool:
+		got, ok := actual.(bool)
+		return ok && got == want
+	}
+	return false
+}
+
+// filterOrder compares a payload value with a literal of the same kind
+func filterOrder(actual, expected interface{}) (int, bool) {
+	switch want := expected.(type) {
+	case string:
+		got, ok := actual.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(got, want), true
+	case float64:
+		got, ok := filterNumber(actual)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case got < want:
+			return -1, true
+		case got > want:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// filterContains matches a substring of a string or an element of an array
+func filterContains(actual, expected interface{}) bool {
+	switch got := actual.(type) {
+	case string:
+		want, ok := expected.(string)
+		return ok && strings.Contains(got, want)
+	case []interface{}:
+		for _, item := range got {
+			if filterEqual(item, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterNumber converts a payload value to a number; header values are parsed
+func filterNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// filterToken is a lexical token of a filter expression
+type filterToken struct {
+	kind string // path, string, number, keyword, op, paren
+	text string
+}
+
+// tokenizeFilter splits a filter expression into tokens
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(' || r == ')':
+			tokens = append(tokens, filterToken{kind: "paren", text: string(r)})
+			i++
+
+		case r == '"':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				value.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			tokens = append(tokens, filterToken{kind: "string", text: value.String()})
+			i = j + 1
+
+		case strings.ContainsRune("=!<>&|", r):
+			j := i + 1
+			if j < len(runes) && strings.ContainsRune("=&|", runes[j]) {
+				j++
+			}
+			op := string(runes[i:j])
+			switch op {
+			case "=":
+				op = "=="
+			case "==", "!=", ">", ">=", "<", "<=", "&&", "||", "!":
+			default:
+				return nil, fmt.Errorf("unknown operator %q in filter", op)
+			}
+			tokens = append(tokens, filterToken{kind: "op", text: op})
+			i = j
+
+		case r == '-' || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, filterToken{kind: "number", text: string(runes[i:j])})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || strings.ContainsRune("_-.", runes[j])) {
+				j++
+			}
+			word := string(runes[i:j])
+			switch word {
+			case "true", "false", "null", "contains":
+				tokens = append(tokens, filterToken{kind: "keyword", text: word})
+			default:
+				tokens = append(tokens, filterToken{kind: "path", text: word})
+			}
+			i = j
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q in filter", r)
+		}
+	}
+
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser over filter tokens
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// peek returns the next token without consuming it
+func (p *filterParser) peek() *filterToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+// parseOr parses a || b || ...
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok != nil && tok.text == "||"; tok = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses a && b && ...
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok != nil && tok.text == "&&"; tok = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseUnary parses negation, parenthesised expressions and comparisons
+func (p *filterParser) parseUnary() (filterNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	switch {
+	case tok.kind == "op" && tok.text == "!":
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{operand: operand}, nil
+
+	case tok.kind == "paren" && tok.text == "(":
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.text != ")" {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		p.pos++
+		return node, nil
+
+	case tok.kind == "path":
+		return p.parseComparison()
+	}
+
+	return nil, fmt.Errorf("unexpected %q in filter", tok.text)
+}
+
+// parseComparison parses path [op literal]
+func (p *filterParser) parseComparison() (filterNode, error) {
+	path, err := parseFilterPath(p.tokens[p.pos].text)
+	if err != nil {
+		return nil, err
+	}
+	p.pos++
+
+	tok := p.peek()
+	if tok == nil || !isComparisonOp(tok.text) {
+		return filterExists{path: path}, nil
+	}
+	op := tok.text
+	p.pos++
+
+	literal := p.peek()
+	if literal == nil {
+		return nil, fmt.Errorf("missing value after %s in filter", op)
+	}
+	p.pos++
+
+	var value interface{}
+	switch {
+	case literal.kind == "string":
+		value = literal.text
+	case literal.kind == "number":
+		n, err := strconv.ParseFloat(literal.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in filter", literal.text)
+		}
+		value = n
+	case literal.text == "true" || literal.text == "false":
+		value = literal.text == "true"
+	case literal.text == "null":
+		value = nil
+	default:
+		return nil, fmt.Errorf("expected a value after %s in filter, got %q", op, literal.text)
+	}
+
+	return filterCompare{path: path, op: op, value: value}, nil
+}
+
+// isComparisonOp reports whether op compares a path with a value
+func isComparisonOp(op string) bool {
+	switch op {
+	case "==", "!=", ">", ">=", "<", "<=", "contains":
+		return true
+	}
+	return false
+}
+
+// parseFilterPath splits a path such as payload.data.items.0.id
+func parseFilterPath(text string) (filterPath, error) {
+	segments := strings.Split(text, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return filterPath{}, fmt.Errorf("invalid filter path %q", text)
+		}
+	}
+
+	switch segments[0] {
+	case "headers":
+		if len(segments) != 2 {
+			return filterPath{}, fmt.Errorf("filter path %q must name a single header", text)
+		}
+		return filterPath{source: "headers", segments: segments[1:]}, nil
+	case "payload":
+		return filterPath{source: "payload", segments: segments[1:]}, nil
+	}
+	return filterPath{source: "payload", segments: segments}, nil
+}
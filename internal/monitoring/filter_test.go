@@ -0,0 +1,133 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{name: "unterminated string", expression: `payload.type == "invoice`},
+		{name: "unknown operator", expression: `payload.a & payload.b`},
+		{name: "unexpected character", expression: `payload.type == 'x'`},
+		{name: "missing value", expression: `payload.amount >=`},
+		{name: "value is a path", expression: `payload.amount > other`},
+		{name: "missing closing paren", expression: `(payload.a == 1`},
+		{name: "stray closing paren", expression: `payload.a == 1)`},
+		{name: "dangling and", expression: `payload.a &&`},
+		{name: "leading operator", expression: `== 1`},
+		{name: "two values", expression: `payload.a == 1 2`},
+		{name: "empty path segment", expression: `payload..type`},
+		{name: "nested header path", expression: `headers.X-Priority.level`},
+		{name: "bare headers", expression: `headers == "x"`},
+		{name: "invalid number", expression: `payload.amount == 1.2.3`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFilter(tt.expression); err == nil {
+				t.Errorf("ParseFilter(%q) succeeded, want an error", tt.expression)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	var payload interface{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "invoice.paid",
+		"amount": 250,
+		"live": true,
+		"refund": null,
+		"customer": {"email": "ops@example.com", "tags": ["vip", "eu"]},
+		"items": [{"sku": "A-1", "qty": 2}, {"sku": "B-2", "qty": 1}]
+	}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{"X-Priority": "high", "X-Attempt": "3"}
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: ``, want: true},
+		{expression: `   `, want: true},
+
+		// Comparisons
+		{expression: `payload.type == "invoice.paid"`, want: true},
+		{expression: `type = "invoice.paid"`, want: true},
+		{expression: `payload.type != "invoice.paid"`, want: false},
+		{expression: `payload.amount >= 250`, want: true},
+		{expression: `payload.amount > 250`, want: false},
+		{expression: `payload.amount < 1000.5`, want: true},
+		{expression: `payload.amount <= -1`, want: false},
+		{expression: `payload.amount == "250"`, want: false},
+		{expression: `payload.type > "invoice"`, want: true},
+		{expression: `payload.live == true`, want: true},
+		{expression: `payload.live == false`, want: false},
+		{expression: `payload.refund == null`, want: true},
+		{expression: `payload.type > 5`, want: false},
+
+		// Nested paths and arrays
+		{expression: `payload.customer.email == "ops@example.com"`, want: true},
+		{expression: `payload.items.1.sku == "B-2"`, want: true},
+		{expression: `payload.items.5.sku == "B-2"`, want: false},
+		{expression: `payload.items.x.sku == "B-2"`, want: false},
+
+		// contains
+		{expression: `payload.customer.email contains "@example"`, want: true},
+		{expression: `payload.customer.tags contains "vip"`, want: true},
+		{expression: `payload.customer.tags contains "us"`, want: false},
+		{expression: `payload.amount contains "2"`, want: false},
+
+		// Existence and missing values
+		{expression: `payload.customer`, want: true},
+		{expression: `payload.refund`, want: false},
+		{expression: `payload.missing`, want: false},
+		{expression: `payload.missing == null`, want: true},
+		{expression: `payload.missing != "x"`, want: true},
+		{expression: `payload.missing == "x"`, want: false},
+		{expression: `payload.missing > 1`, want: false},
+
+		// Headers are case-insensitive and numeric headers compare as numbers
+		{expression: `headers.X-Priority == "high"`, want: true},
+		{expression: `headers.x-priority == "high"`, want: true},
+		{expression: `headers.X-Attempt >= 3`, want: true},
+		{expression: `headers.X-Missing`, want: false},
+
+		// Boolean logic and precedence
+		{expression: `payload.type == "invoice.paid" && payload.amount >= 100`, want: true},
+		{expression: `payload.type == "invoice.failed" || payload.amount >= 100`, want: true},
+		{expression: `payload.type == "invoice.failed" || payload.amount >= 100 && payload.live == false`, want: false},
+		{expression: `(payload.type == "invoice.failed" || payload.amount >= 100) && headers.X-Priority == "high"`, want: true},
+		{expression: `!payload.live`, want: false},
+		{expression: `!(payload.amount < 100)`, want: true},
+		{expression: `!!payload.live`, want: true},
+		{expression: `payload.type == "a \"quoted\" value"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expression)
+			if err != nil {
+				t.Fatalf("ParseFilter: %v", err)
+			}
+			if got := filter.Match(payload, headers); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterStringEscapes(t *testing.T) {
+	filter, err := ParseFilter(`payload.message == "say \"hi\""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.Match(map[string]interface{}{"message": `say "hi"`}, nil) {
+		t.Error("escaped quotes were not unescaped")
+	}
+}
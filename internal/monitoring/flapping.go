@@ -0,0 +1,120 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// minFlapSamples is how many checks are needed before a monitor can be considered flapping
+const minFlapSamples = 5
+
+// ValidateFlapDetection checks a monitor's flap detection settings
+func ValidateFlapDetection(monitor *database.Monitor) error {
+	if monitor.FlapWindow < minFlapSamples || monitor.FlapWindow > 100 {
+		return fmt.Errorf("flap_window must be between %d and 100 checks", minFlapSamples)
+	}
+
+	if monitor.FlapStartPercent <= 0 || monitor.FlapStartPercent > 100 {
+		return fmt.Errorf("flap_start_percent must be above 0 and at most 100")
+	}
+
+	// The gap between the thresholds keeps a monitor near the limit from toggling in and out
+	if monitor.FlapStopPercent < 0 || monitor.FlapStopPercent >= monitor.FlapStartPercent {
+		return fmt.Errorf("flap_stop_percent must be at least 0 and below flap_start_percent")
+	}
+
+	return nil
+}
+
+// updateFlapState works out whether a monitor is flapping from its recent checks, raising a
+// single flapping alert when it starts and resolving it once the monitor is stable again.
+// It reports whether the monitor is flapping, in which case up/down alerts are suppressed.
+func (s *Service) updateFlapState(monitor *database.Monitor) bool {
+	// The scheduled copy of the monitor may be stale, so read the current state
+	var current database.Monitor
+	if err := s.db.Select("id", "flap_detection", "flap_window", "flap_start_percent", "flap_stop_percent", "is_flapping").
+		First(&current, monitor.ID).Error; err != nil {
+		s.log.Errorf("Failed to load flap state of monitor %d: %v", monitor.ID, err)
+		return false
+	}
+
+	if !current.FlapDetection {
+		if current.IsFlapping {
+			s.setFlapping(monitor, false)
+		}
+		return false
+	}
+
+	var statuses []string
+	if err := s.db.Model(&database.MonitorCheck{}).
+		Where("monitor_id = ?", monitor.ID).
+		Order("checked_at DESC").
+		Limit(current.FlapWindow).
+		Pluck("status", &statuses).Error; err != nil {
+		s.log.Errorf("Failed to load recent checks of monitor %d: %v", monitor.ID, err)
+		return current.IsFlapping
+	}
+
+	changes, samples := flapChanges(statuses)
+	if samples < minFlapSamples {
+		return current.IsFlapping
+	}
+	percent := float64(changes) / float64(samples-1) * 100
+
+	switch {
+	case !current.IsFlapping && percent >= current.FlapStartPercent:
+		s.setFlapping(monitor, true)
+
+		// One flapping alert replaces the storm of individual down alerts
+		s.resolveAlerts(monitor.ID, "down")
+		s.createAlert(monitor, "flapping",
+			fmt.Sprintf("Monitor %s is flapping: %d state changes in its last %d checks", monitor.Name, changes, len(statuses)),
+			"medium")
+		return true
+
+	case current.IsFlapping && percent <= current.FlapStopPercent:
+		s.setFlapping(monitor, false)
+		return false
+	}
+
+	return current.IsFlapping
+}
+
+// flapChanges counts the changes between up and down in a run of check statuses. Warning
+// and unknown results are skipped, so they neither count as a change nor break one up.
+// It also returns how many up and down checks were considered.
+func flapChanges(statuses []string) (changes, samples int) {
+	previous := ""
+	for _, status := range statuses {
+		if status != "up" && status != "down" {
+			continue
+		}
+		if previous != "" && status != previous {
+			changes++
+		}
+		previous = status
+		samples++
+	}
+	return changes, samples
+}
+
+// setFlapping stores whether a monitor is flapping, resolving its flapping alert when it stops
+func (s *Service) setFlapping(monitor *database.Monitor, flapping bool) {
+	updates := map[string]interface{}{
+		"is_flapping":    flapping,
+		"flapping_since": nil,
+	}
+	if flapping {
+		updates["flapping_since"] = time.Now()
+	}
+
+	if err := s.db.Model(&database.Monitor{}).Where("id = ?", monitor.ID).Updates(updates).Error; err != nil {
+		s.log.Errorf("Failed to update flap state of monitor %d: %v", monitor.ID, err)
+	}
+
+	if !flapping {
+		s.resolveAlerts(monitor.ID, "flapping")
+	}
+}
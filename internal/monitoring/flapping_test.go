@@ -0,0 +1,60 @@
+package monitoring
+
+import (
+	"testing"
+
+	"vigil/internal/database"
+)
+
+func TestFlapChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []string
+		wantChanges int
+		wantSamples int
+	}{
+		{name: "steady", statuses: []string{"up", "up", "up", "up", "up"}, wantChanges: 0, wantSamples: 5},
+		{name: "alternating", statuses: []string{"up", "down", "up", "down", "up"}, wantChanges: 4, wantSamples: 5},
+		{name: "warnings are not changes", statuses: []string{"up", "warning", "up", "warning", "up"}, wantChanges: 0, wantSamples: 3},
+		{name: "unknown is not a change", statuses: []string{"down", "unknown", "down", "unknown", "down"}, wantChanges: 0, wantSamples: 3},
+		{name: "change across a warning counts once", statuses: []string{"up", "warning", "down", "down"}, wantChanges: 1, wantSamples: 3},
+		{name: "only warnings", statuses: []string{"warning", "unknown", "warning"}, wantChanges: 0, wantSamples: 0},
+		{name: "empty", wantChanges: 0, wantSamples: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, samples := flapChanges(tt.statuses)
+			if changes != tt.wantChanges || samples != tt.wantSamples {
+				t.Errorf("flapChanges = %d changes of %d samples, want %d of %d", changes, samples, tt.wantChanges, tt.wantSamples)
+			}
+		})
+	}
+}
+
+func TestValidateFlapDetection(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  int
+		start   float64
+		stop    float64
+		wantErr bool
+	}{
+		{name: "defaults", window: 20, start: 50, stop: 25},
+		{name: "stop of zero", window: 20, start: 50, stop: 0},
+		{name: "stop equals start", window: 20, start: 50, stop: 50, wantErr: true},
+		{name: "stop above start", window: 20, start: 30, stop: 40, wantErr: true},
+		{name: "negative stop", window: 20, start: 50, stop: -1, wantErr: true},
+		{name: "start above 100", window: 20, start: 101, stop: 25, wantErr: true},
+		{name: "window too small", window: minFlapSamples - 1, start: 50, stop: 25, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFlapDetection(&database.Monitor{FlapWindow: tt.window, FlapStartPercent: tt.start, FlapStopPercent: tt.stop})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFlapDetection error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
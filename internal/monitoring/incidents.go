@@ -0,0 +1,160 @@
+package monitoring
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// incidentStatuses are the states an incident moves through
+var incidentStatuses = map[string]bool{
+	"investigating": true,
+	"identified":    true,
+	"monitoring":    true,
+	"resolved":      true,
+}
+
+// severityRanks orders alert severities from least to most severe
+var severityRanks = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// ValidateIncidentStatus checks an incident status
+func ValidateIncidentStatus(status string) error {
+	if !incidentStatuses[status] {
+		return fmt.Errorf("status must be investigating, identified, monitoring or resolved")
+	}
+	return nil
+}
+
+// ValidateIncidentRule checks an incident rule's grouping settings
+func ValidateIncidentRule(rule *database.IncidentRule) error {
+	switch rule.GroupBy {
+	case "host", "group", "time":
+	default:
+		return fmt.Errorf("group_by must be host, group or time")
+	}
+
+	if rule.WindowMinutes < 1 || rule.WindowMinutes > 24*60 {
+		return fmt.Errorf("window_minutes must be between 1 and 1440")
+	}
+
+	return nil
+}
+
+// groupAlert finds the incident a new alert belongs to using the organization's active
+// rules, in the order they were created. It returns the open incident the alert joins, or
+// a new, unsaved incident when the alert should open one. Nil means no rule applies.
+func (s *Service) groupAlert(alert *database.Alert, organizationID uint) (incident *database.Incident, opened bool) {
+	var rules []database.IncidentRule
+	if err := s.db.Where("organization_id = ? AND is_active = ?", organizationID, true).Order("id").Find(&rules).Error; err != nil {
+		s.log.Errorf("Failed to load incident rules: %v", err)
+		return nil, false
+	}
+	if len(rules) == 0 {
+		return nil, false
+	}
+
+	var monitor *database.Monitor
+	if alert.MonitorID != nil {
+		monitor = &database.Monitor{}
+		if err := s.db.First(monitor, *alert.MonitorID).Error; err != nil {
+			monitor = nil
+		}
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		key, title := incidentKey(rule, monitor, alert)
+		if key == "" {
+			continue
+		}
+
+		var existing database.Incident
+		if err := s.db.Where("organization_id = ? AND group_key = ? AND status <> ? AND last_alert_at >= ?",
+			organizationID, key, "resolved", alert.CreatedAt.Add(-time.Duration(rule.WindowMinutes)*time.Minute)).
+			Order("last_alert_at DESC").
+			First(&existing).Error; err == nil {
+			return &existing, false
+		}
+
+		ruleID := rule.ID
+		return &database.Incident{
+			OrganizationID: organizationID,
+			Title:          title,
+			Status:         "investigating",
+			Severity:       alert.Severity,
+			RuleID:         &ruleID,
+			GroupKey:       key,
+			StartedAt:      alert.CreatedAt,
+			LastAlertAt:    alert.CreatedAt,
+		}, true
+	}
+
+	return nil, false
+}
+
+// joinIncident records that an alert was added to an existing incident
+func (s *Service) joinIncident(incident *database.Incident, alert *database.Alert) {
+	updates := map[string]interface{}{"last_alert_at": alert.CreatedAt}
+	if severityRanks[alert.Severity] > severityRanks[incident.Severity] {
+		updates["severity"] = alert.Severity
+	}
+
+	if err := s.db.Model(&database.Incident{}).Where("id = ?", incident.ID).Updates(updates).Error; err != nil {
+		s.log.Errorf("Failed to update incident %d: %v", incident.ID, err)
+	}
+
+	s.recordAlertEvent(alert.ID, "grouped", fmt.Sprintf("Grouped into incident #%d: %s", incident.ID, incident.Title))
+}
+
+// incidentKey returns the key alerts are grouped by under a rule, and the title of an
+// incident opened for it. The key is empty when the rule doesn't apply to the alert.
+func incidentKey(rule *database.IncidentRule, monitor *database.Monitor, alert *database.Alert) (string, string) {
+	switch rule.GroupBy {
+	case "host":
+		if monitor == nil {
+			return "", ""
+		}
+		host := monitorHost(monitor)
+		if host == "" {
+			return "", ""
+		}
+		return "host:" + host, "Alerts on " + host
+
+	case "group":
+		if monitor == nil || monitor.Group == "" {
+			return "", ""
+		}
+		return "group:" + monitor.Group, "Alerts in group " + monitor.Group
+
+	case "time":
+		return fmt.Sprintf("time:%d", rule.ID), alert.Message
+	}
+	return "", ""
+}
+
+// monitorHost returns the lower-cased host a monitor checks
+func monitorHost(monitor *database.Monitor) string {
+	if strings.Contains(monitor.URL, "://") {
+		target, err := url.Parse(monitor.URL)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(target.Hostname())
+	}
+
+	// SSL monitors store host:port
+	host, _, err := net.SplitHostPort(monitor.URL)
+	if err != nil {
+		host = monitor.URL
+	}
+	return strings.ToLower(host)
+}
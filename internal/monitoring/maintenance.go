@@ -0,0 +1,197 @@
+package monitoring
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+)
+
+// maxMaintenanceDuration bounds a single maintenance occurrence
+const maxMaintenanceDuration = 7 * 24 * time.Hour
+
+// MaintenanceOccurrence is one period of a maintenance window
+type MaintenanceOccurrence struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ParseTags splits a comma separated tag list, dropping blanks and duplicates
+func ParseTags(tags string) []string {
+	var parsed []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		parsed = append(parsed, tag)
+	}
+	return parsed
+}
+
+// ValidateMaintenanceWindow checks a window's timing and recurrence. Monitors are checked
+// by the caller; a window must cover at least one monitor or tag.
+func ValidateMaintenanceWindow(window *database.MaintenanceWindow) error {
+	if window.StartsAt.IsZero() {
+		return fmt.Errorf("starts_at is required")
+	}
+
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+	if duration <= 0 || duration > maxMaintenanceDuration {
+		return fmt.Errorf("duration_minutes must be between 1 and %d", int(maxMaintenanceDuration.Minutes()))
+	}
+
+	if _, err := maintenanceRule(window); err != nil {
+		return err
+	}
+
+	if len(window.Monitors) == 0 && len(ParseTags(window.Tags)) == 0 {
+		return fmt.Errorf("a maintenance window must cover at least one monitor or tag")
+	}
+
+	return nil
+}
+
+// MaintenanceOccurrenceAt returns the occurrence of a window in progress at a point in time
+func MaintenanceOccurrenceAt(window *database.MaintenanceWindow, at time.Time) (MaintenanceOccurrence, bool) {
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+
+	rule, err := maintenanceRule(window)
+	if err != nil {
+		return MaintenanceOccurrence{}, false
+	}
+
+	start := window.StartsAt
+	if rule != nil {
+		start = rule.Before(at, true)
+		if start.IsZero() {
+			return MaintenanceOccurrence{}, false
+		}
+	}
+
+	if at.Before(start) || !at.Before(start.Add(duration)) {
+		return MaintenanceOccurrence{}, false
+	}
+	return MaintenanceOccurrence{Start: start, End: start.Add(duration)}, true
+}
+
+// UpcomingMaintenance returns up to limit occurrences of a window that end after a point in time
+func UpcomingMaintenance(window *database.MaintenanceWindow, after time.Time, limit int) []MaintenanceOccurrence {
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+
+	rule, err := maintenanceRule(window)
+	if err != nil {
+		return nil
+	}
+
+	if rule == nil {
+		if !window.StartsAt.Add(duration).After(after) {
+			return nil
+		}
+		return []MaintenanceOccurrence{{Start: window.StartsAt, End: window.StartsAt.Add(duration)}}
+	}
+
+	var occurrences []MaintenanceOccurrence
+	if current, ok := MaintenanceOccurrenceAt(window, after); ok {
+		occurrences = append(occurrences, current)
+	}
+
+	next := rule.Iterator()
+	for len(occurrences) < limit {
+		start, ok := next()
+		if !ok {
+			break
+		}
+		if !start.After(after) {
+			continue
+		}
+		occurrences = append(occurrences, MaintenanceOccurrence{Start: start, End: start.Add(duration)})
+	}
+
+	return occurrences
+}
+
+// maintenanceRule builds a window's recurrence in its timezone, or nil for one-off windows
+func maintenanceRule(window *database.MaintenanceWindow) (*rrule.RRule, error) {
+	location, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", window.Timezone)
+	}
+
+	source := strings.TrimSpace(window.RRule)
+	if source == "" {
+		return nil, nil
+	}
+	source = strings.TrimPrefix(strings.TrimPrefix(source, "RRULE:"), "rrule:")
+
+	option, err := rrule.StrToROptionInLocation(source, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule: %v", err)
+	}
+
+	// Occurrences are found by walking the rule from its start, which finer frequencies
+	// make too costly for a check that runs on every monitor check
+	if option.Freq > rrule.HOURLY {
+		return nil, fmt.Errorf("invalid rrule: FREQ must be HOURLY or less frequent")
+	}
+
+	// Recurrences repeat at the first occurrence's local time, across DST changes
+	option.Dtstart = window.StartsAt.In(location)
+
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule: %v", err)
+	}
+	return rule, nil
+}
+
+// maintenanceEnd reports whether a monitor is in a maintenance window right now, and
+// when the latest-ending window covering it ends
+func (s *Service) maintenanceEnd(monitor *database.Monitor) (time.Time, bool) {
+	var windows []database.MaintenanceWindow
+	if err := s.db.Preload("Monitors", func(db *gorm.DB) *gorm.DB { return db.Select("monitors.id") }).
+		Where("organization_id = ?", monitor.OrganizationID).
+		Find(&windows).Error; err != nil {
+		s.log.Errorf("Failed to load maintenance windows: %v", err)
+		return time.Time{}, false
+	}
+
+	monitorTags := make(map[string]bool)
+	for _, tag := range ParseTags(monitor.Tags) {
+		monitorTags[tag] = true
+	}
+
+	now := time.Now()
+	var end time.Time
+	for i := range windows {
+		if !maintenanceCovers(&windows[i], monitor.ID, monitorTags) {
+			continue
+		}
+		if occurrence, ok := MaintenanceOccurrenceAt(&windows[i], now); ok && occurrence.End.After(end) {
+			end = occurrence.End
+		}
+	}
+
+	return end, !end.IsZero()
+}
+
+// maintenanceCovers reports whether a window covers a monitor directly or by one of its tags
+func maintenanceCovers(window *database.MaintenanceWindow, monitorID uint, monitorTags map[string]bool) bool {
+	for _, monitor := range window.Monitors {
+		if monitor.ID == monitorID {
+			return true
+		}
+	}
+	for _, tag := range ParseTags(window.Tags) {
+		if monitorTags[tag] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+
+	"vigil/internal/database"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return location
+}
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	start := time.Date(2024, 3, 3, 9, 0, 0, 0, time.UTC)
+	monitors := []database.Monitor{{ID: 1}}
+
+	tests := []struct {
+		name    string
+		window  database.MaintenanceWindow
+		wantErr bool
+	}{
+		{name: "one-off", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 60, Timezone: "UTC", Monitors: monitors}},
+		{name: "recurring by tag", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 60, Timezone: "Europe/Berlin", RRule: "FREQ=WEEKLY;BYDAY=SU", Tags: "db"}},
+		{name: "RRULE: prefix", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 60, Timezone: "UTC", RRule: "RRULE:FREQ=DAILY;COUNT=3", Tags: "db"}},
+		{name: "missing start", window: database.MaintenanceWindow{DurationMinutes: 60, Timezone: "UTC", Monitors: monitors}, wantErr: true},
+		{name: "zero duration", window: database.MaintenanceWindow{StartsAt: start, Timezone: "UTC", Monitors: monitors}, wantErr: true},
+		{name: "longer than a week", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 7*24*60 + 1, Timezone: "UTC", Monitors: monitors}, wantErr: true},
+		{name: "unknown timezone", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 60, Timezone: "Mars/Olympus", Monitors: monitors}, wantErr: true},
+		{name: "invalid rrule", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 60, Timezone: "UTC", RRule: "FREQ=SOMETIMES", Monitors: monitors}, wantErr: true},
+		{name: "hourly", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 30, Timezone: "UTC", RRule: "FREQ=HOURLY;INTERVAL=6", Monitors: monitors}},
+		{name: "minutely", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 1, Timezone: "UTC", RRule: "FREQ=MINUTELY;INTERVAL=5", Monitors: monitors}, wantErr: true},
+		{name: "secondly", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 1, Timezone: "UTC", RRule: "FREQ=SECONDLY", Monitors: monitors}, wantErr: true},
+		{name: "covers nothing", window: database.MaintenanceWindow{StartsAt: start, DurationMinutes: 60, Timezone: "UTC", Tags: " , "}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaintenanceWindow(&tt.window)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMaintenanceWindow error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpcomingMaintenance(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+
+	tests := []struct {
+		name   string
+		window database.MaintenanceWindow
+		after  time.Time
+		limit  int
+		want   []time.Time // occurrence starts
+	}{
+		{
+			// US clocks spring forward on 10 March 2024; occurrences stay at 09:00 local
+			name: "weekly across spring forward",
+			window: database.MaintenanceWindow{
+				StartsAt: time.Date(2024, 3, 3, 9, 0, 0, 0, newYork), DurationMinutes: 60,
+				Timezone: "America/New_York", RRule: "FREQ=WEEKLY;BYDAY=SU",
+			},
+			after: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			limit: 3,
+			want: []time.Time{
+				time.Date(2024, 3, 3, 14, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 17, 13, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// ...and fall back on 3 November 2024
+			name: "daily across fall back",
+			window: database.MaintenanceWindow{
+				StartsAt: time.Date(2024, 11, 2, 1, 30, 0, 0, newYork), DurationMinutes: 30,
+				Timezone: "America/New_York", RRule: "FREQ=DAILY",
+			},
+			after: time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC),
+			limit: 3,
+			want: []time.Time{
+				time.Date(2024, 11, 2, 5, 30, 0, 0, time.UTC),
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC),
+				time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "several days a week",
+			window: database.MaintenanceWindow{
+				StartsAt: time.Date(2024, 1, 2, 22, 0, 0, 0, time.UTC), DurationMinutes: 120,
+				Timezone: "UTC", RRule: "RRULE:FREQ=WEEKLY;BYDAY=TU,TH",
+			},
+			after: time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC),
+			limit: 3,
+			want: []time.Time{
+				// The Tuesday occurrence is still in progress
+				time.Date(2024, 1, 2, 22, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 4, 22, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 9, 22, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "count runs out",
+			window: database.MaintenanceWindow{
+				StartsAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), DurationMinutes: 60,
+				Timezone: "UTC", RRule: "FREQ=DAILY;COUNT=3",
+			},
+			after: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			limit: 5,
+			want:  []time.Time{time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "one-off not yet ended",
+			window: database.MaintenanceWindow{
+				StartsAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), DurationMinutes: 60, Timezone: "UTC",
+			},
+			after: time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC),
+			limit: 5,
+			want:  []time.Time{time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "one-off already over",
+			window: database.MaintenanceWindow{
+				StartsAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), DurationMinutes: 60, Timezone: "UTC",
+			},
+			after: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+			limit: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UpcomingMaintenance(&tt.window, tt.after, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %d", len(got), got, len(tt.want))
+			}
+			duration := time.Duration(tt.window.DurationMinutes) * time.Minute
+			for i, occurrence := range got {
+				if !occurrence.Start.Equal(tt.want[i]) {
+					t.Errorf("occurrence %d starts %v, want %v", i, occurrence.Start.UTC(), tt.want[i])
+				}
+				if occurrence.End.Sub(occurrence.Start) != duration {
+					t.Errorf("occurrence %d lasts %v, want %v", i, occurrence.End.Sub(occurrence.Start), duration)
+				}
+			}
+		})
+	}
+}
+
+func TestMaintenanceOccurrenceAt(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	// Sundays 02:00-04:00 Berlin time, which moves from UTC+1 to UTC+2 on 31 March 2024
+	weekly := database.MaintenanceWindow{
+		StartsAt: time.Date(2024, 3, 3, 2, 0, 0, 0, berlin), DurationMinutes: 120,
+		Timezone: "Europe/Berlin", RRule: "FREQ=WEEKLY;BYDAY=SU",
+	}
+	oneOff := database.MaintenanceWindow{
+		StartsAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), DurationMinutes: 30, Timezone: "UTC",
+	}
+
+	tests := []struct {
+		name      string
+		window    *database.MaintenanceWindow
+		at        time.Time
+		wantStart time.Time
+		wantOK    bool
+	}{
+		{name: "first occurrence", window: &weekly, at: time.Date(2024, 3, 3, 2, 30, 0, 0, berlin), wantStart: time.Date(2024, 3, 3, 1, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "start is inclusive", window: &weekly, at: time.Date(2024, 3, 10, 1, 0, 0, 0, time.UTC), wantStart: time.Date(2024, 3, 10, 1, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "end is exclusive", window: &weekly, at: time.Date(2024, 3, 10, 3, 0, 0, 0, time.UTC)},
+		{name: "between occurrences", window: &weekly, at: time.Date(2024, 3, 13, 2, 30, 0, 0, berlin)},
+		{name: "before the first occurrence", window: &weekly, at: time.Date(2024, 3, 1, 2, 30, 0, 0, berlin)},
+		{name: "after DST in local time", window: &weekly, at: time.Date(2024, 4, 7, 3, 59, 0, 0, berlin), wantStart: time.Date(2024, 4, 7, 0, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "one-off in progress", window: &oneOff, at: time.Date(2024, 5, 1, 12, 29, 0, 0, time.UTC), wantStart: oneOff.StartsAt, wantOK: true},
+		{name: "one-off over", window: &oneOff, at: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrence, ok := MaintenanceOccurrenceAt(tt.window, tt.at)
+			if ok != tt.wantOK {
+				t.Fatalf("in maintenance = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !occurrence.Start.Equal(tt.wantStart) {
+				t.Errorf("occurrence starts %v, want %v", occurrence.Start.UTC(), tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags(" DB, api,,db , Edge ")
+	want := []string{"db", "api", "edge"}
+	if len(got) != len(want) {
+		t.Fatalf("ParseTags = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParseTags = %v, want %v", got, want)
+		}
+	}
+}
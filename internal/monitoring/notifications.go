@@ -0,0 +1,267 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"vigil/internal/database"
+	"vigil/internal/services"
+)
+
+// notificationTimeout bounds a single outbound notification request
+const notificationTimeout = 10 * time.Second
+
+// channelConfig is the union of the settings stored on notification channels
+type channelConfig struct {
+	Email      string `json:"email"`
+	WebhookURL string `json:"webhookUrl"` // slack, discord
+	Channel    string `json:"channel"`    // slack
+	URL        string `json:"url"`        // webhook
+	ScheduleID uint   `json:"scheduleId"` // on_call
+}
+
+// notifyChannel records a notification for an alert on a channel and sends it in the
+// background. It reports whether the notification was recorded.
+func (s *Service) notifyChannel(alert *database.Alert, channel *database.NotificationChannel, escalationLevel, reminder int) bool {
+	channelID := channel.ID
+	notification := database.AlertNotification{
+		AlertID:               alert.ID,
+		NotificationChannelID: &channelID,
+		EscalationLevel:       escalationLevel,
+		ReminderNumber:        reminder,
+		SentAt:                time.Now(),
+		Status:                "pending",
+	}
+
+	if err := s.db.Create(&notification).Error; err != nil {
+		s.log.Errorf("Failed to create notification: %v", err)
+		return false
+	}
+
+	go s.sendNotification(&notification, *channel, *alert)
+	return true
+}
+
+// notifyUser pages a user through each of their contact methods in the background, falling
+// back to their account email when they have none. It reports whether any notification
+// was recorded.
+func (s *Service) notifyUser(alert *database.Alert, user *database.User, escalationLevel, reminder int) bool {
+	methods := s.contactMethods(user)
+	alertCopy := *alert
+	notified := false
+	for _, method := range methods {
+		method := method
+		userID := user.ID
+		notification := database.AlertNotification{
+			AlertID:         alert.ID,
+			UserID:          &userID,
+			EscalationLevel: escalationLevel,
+			ReminderNumber:  reminder,
+			SentAt:          time.Now(),
+			Status:          "pending",
+		}
+		if method.ID != 0 {
+			methodID := method.ID
+			notification.ContactMethodID = &methodID
+		}
+
+		if err := s.db.Create(&notification).Error; err != nil {
+			s.log.Errorf("Failed to create notification: %v", err)
+			continue
+		}
+
+		notified = true
+
+		config := contactMethodConfig(&method)
+		go s.finishNotification(&notification, func() error {
+			return s.dispatchNotification(method.Type, config, &alertCopy)
+		})
+	}
+	return notified
+}
+
+// sendNotification delivers a notification through its channel
+func (s *Service) sendNotification(notification *database.AlertNotification, channel database.NotificationChannel, alert database.Alert) {
+	s.finishNotification(notification, func() error {
+		var config channelConfig
+		if err := json.Unmarshal([]byte(channel.Config), &config); err != nil {
+			return fmt.Errorf("invalid channel config: %v", err)
+		}
+		return s.dispatchNotification(channel.Type, config, &alert)
+	})
+}
+
+// dispatchNotification sends an alert to a destination of the given type
+func (s *Service) dispatchNotification(destinationType string, config channelConfig, alert *database.Alert) error {
+	switch destinationType {
+	case "email":
+		return s.sendAlertEmail(config.Email, alert)
+	case "slack":
+		return postNotification(config.WebhookURL, map[string]interface{}{
+			"text":    alertSummary(alert),
+			"channel": config.Channel,
+		})
+	case "discord":
+		return postNotification(config.WebhookURL, map[string]interface{}{
+			"content": alertSummary(alert),
+		})
+	case "webhook":
+		return postNotification(config.URL, map[string]interface{}{
+			"event": "alert",
+			"alert": alert,
+		})
+	case "on_call":
+		user := s.onCallUser(s.alertOrganizationID(alert), config.ScheduleID)
+		if user == nil {
+			return fmt.Errorf("nobody is on call on schedule %d", config.ScheduleID)
+		}
+		return s.pageUser(user, alert)
+	}
+	return fmt.Errorf("unsupported notification type %q", destinationType)
+}
+
+// pageUser sends an alert to every contact method of a user, failing only when none of them worked
+func (s *Service) pageUser(user *database.User, alert *database.Alert) error {
+	methods := s.contactMethods(user)
+
+	var lastErr error
+	failed := 0
+	for i := range methods {
+		if err := s.dispatchNotification(methods[i].Type, contactMethodConfig(&methods[i]), alert); err != nil {
+			lastErr = err
+			failed++
+		}
+	}
+
+	if failed == len(methods) {
+		return fmt.Errorf("could not reach %s through any contact method: %v", user.Email, lastErr)
+	}
+	return nil
+}
+
+// contactMethods returns a user's contact methods, or their account email when they have none
+func (s *Service) contactMethods(user *database.User) []database.ContactMethod {
+	var methods []database.ContactMethod
+	if err := s.db.Where("user_id = ?", user.ID).Order("id").Find(&methods).Error; err != nil {
+		s.log.Errorf("Failed to load contact methods for user %d: %v", user.ID, err)
+	}
+	if len(methods) == 0 {
+		methods = []database.ContactMethod{{UserID: user.ID, Type: "email", Address: user.Email}}
+	}
+	return methods
+}
+
+// contactMethodConfig maps a contact method's address onto the matching channel setting
+func contactMethodConfig(method *database.ContactMethod) channelConfig {
+	switch method.Type {
+	case "email":
+		return channelConfig{Email: method.Address}
+	case "slack", "discord":
+		return channelConfig{WebhookURL: method.Address}
+	}
+	return channelConfig{URL: method.Address}
+}
+
+// ValidateContactMethod checks a contact method's type and address
+func ValidateContactMethod(method *database.ContactMethod) error {
+	switch method.Type {
+	case "email":
+		if _, err := mail.ParseAddress(method.Address); err != nil {
+			return fmt.Errorf("invalid email address %q", method.Address)
+		}
+	case "slack", "discord", "webhook":
+		target, err := url.Parse(method.Address)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("invalid URL %q", method.Address)
+		}
+	default:
+		return fmt.Errorf("type must be email, slack, discord or webhook")
+	}
+	return nil
+}
+
+// finishNotification runs send and stores whether the notification went out
+func (s *Service) finishNotification(notification *database.AlertNotification, send func() error) {
+	updates := map[string]interface{}{
+		"status":        "sent",
+		"sent_at":       time.Now(),
+		"error_message": "",
+	}
+
+	if err := send(); err != nil {
+		updates["status"] = "failed"
+		updates["error_message"] = err.Error()
+		s.log.Warnf("Failed to send notification %d for alert %d: %v", notification.ID, notification.AlertID, err)
+	}
+
+	if err := s.db.Model(&database.AlertNotification{}).Where("id = ?", notification.ID).Updates(updates).Error; err != nil {
+		s.log.Errorf("Failed to update notification status: %v", err)
+	}
+}
+
+// sendAlertEmail emails an alert to a single address
+func (s *Service) sendAlertEmail(to string, alert *database.Alert) error {
+	if to == "" {
+		return fmt.Errorf("no email address configured")
+	}
+
+	return s.email.SendEmail(services.EmailData{
+		To:      to,
+		Subject: "[Vigil] " + alertSummary(alert),
+		Body:    alert.Message,
+		HTML: fmt.Sprintf("<p><strong>%s</strong> alert (%s)</p><p>%s</p><p>Raised at %s</p>",
+			html.EscapeString(alert.Severity), html.EscapeString(alert.Type),
+			html.EscapeString(alert.Message), alert.CreatedAt.Format(time.RFC1123)),
+	})
+}
+
+// postNotification posts a JSON notification to a chat or webhook URL
+func postNotification(targetURL string, payload interface{}) error {
+	if targetURL == "" {
+		return fmt.Errorf("no URL configured")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Vigil-Notifier/1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification target responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// alertSummary is the one-line text used for chat notifications
+func alertSummary(alert *database.Alert) string {
+	summary := fmt.Sprintf("[%s] %s: %s", alert.Severity, alert.Type, alert.Message)
+	if alert.IncidentID != nil {
+		summary = fmt.Sprintf("Incident #%d %s", *alert.IncidentID, summary)
+	}
+	return summary
+}
@@ -0,0 +1,148 @@
+package monitoring
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+)
+
+// OnCallShift is who is on call at a point in time and for how long
+type OnCallShift struct {
+	ScheduleID uint           `json:"schedule_id"`
+	UserID     *uint          `json:"user_id"` // nil when nobody is on call
+	User       *database.User `json:"user"`
+	Start      *time.Time     `json:"start"`
+	End        *time.Time     `json:"end"`
+	Override   bool           `json:"override"` // the shift comes from an override, not the rotation
+}
+
+// ValidateOnCallSchedule checks a schedule's rotation settings
+func ValidateOnCallSchedule(schedule *database.OnCallSchedule) error {
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", schedule.Timezone)
+	}
+
+	if rotationDays(schedule.RotationType) == 0 {
+		return fmt.Errorf("rotation_type must be daily or weekly")
+	}
+
+	if schedule.RotationStart.IsZero() {
+		return fmt.Errorf("rotation_start is required")
+	}
+
+	if len(schedule.Participants) == 0 {
+		return fmt.Errorf("a schedule needs at least one participant")
+	}
+
+	return nil
+}
+
+// ResolveOnCall works out who is on call on a schedule at a point in time. The schedule's
+// participants and overrides must be loaded. Overrides take precedence over the rotation,
+// and the most recently created override wins when several overlap.
+func ResolveOnCall(schedule *database.OnCallSchedule, at time.Time) OnCallShift {
+	shift := OnCallShift{ScheduleID: schedule.ID}
+
+	var override *database.OnCallOverride
+	for i := range schedule.Overrides {
+		candidate := &schedule.Overrides[i]
+		if at.Before(candidate.StartsAt) || !at.Before(candidate.EndsAt) {
+			continue
+		}
+		if override == nil || candidate.CreatedAt.After(override.CreatedAt) {
+			override = candidate
+		}
+	}
+
+	if override != nil {
+		userID := override.UserID
+		start, end := override.StartsAt, override.EndsAt
+		shift.UserID = &userID
+		shift.User = override.User
+		shift.Start = &start
+		shift.End = &end
+		shift.Override = true
+		return shift
+	}
+
+	days := rotationDays(schedule.RotationType)
+	if len(schedule.Participants) == 0 || days == 0 || at.Before(schedule.RotationStart) {
+		return shift
+	}
+
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	// Step in calendar days so handoffs keep their local time across DST changes
+	first := schedule.RotationStart.In(location)
+	n := int(at.Sub(first) / (time.Duration(days) * 24 * time.Hour))
+	for !first.AddDate(0, 0, (n+1)*days).After(at) {
+		n++
+	}
+	for n > 0 && first.AddDate(0, 0, n*days).After(at) {
+		n--
+	}
+
+	participants := make([]database.OnCallParticipant, len(schedule.Participants))
+	copy(participants, schedule.Participants)
+	sort.Slice(participants, func(i, j int) bool { return participants[i].Position < participants[j].Position })
+
+	participant := participants[n%len(participants)]
+	start := first.AddDate(0, 0, n*days)
+	end := first.AddDate(0, 0, (n+1)*days)
+	shift.UserID = &participant.UserID
+	shift.User = participant.User
+	shift.Start = &start
+	shift.End = &end
+	return shift
+}
+
+// PreloadOnCall loads what ResolveOnCall needs, skipping overrides that have ended
+func PreloadOnCall(query *gorm.DB, since time.Time) *gorm.DB {
+	return query.
+		Preload("Participants", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Participants.User").
+		Preload("Overrides", func(db *gorm.DB) *gorm.DB { return db.Where("ends_at > ?", since).Order("starts_at") }).
+		Preload("Overrides.User")
+}
+
+// onCallUser returns the user on call right now on a schedule of the organization, or nil
+func (s *Service) onCallUser(organizationID, scheduleID uint) *database.User {
+	now := time.Now()
+
+	var schedule database.OnCallSchedule
+	if err := PreloadOnCall(s.db.DB, now).
+		Where("id = ? AND organization_id = ?", scheduleID, organizationID).
+		First(&schedule).Error; err != nil {
+		s.log.Warnf("Failed to load on-call schedule %d: %v", scheduleID, err)
+		return nil
+	}
+
+	shift := ResolveOnCall(&schedule, now)
+	if shift.UserID == nil {
+		return nil
+	}
+
+	var user database.User
+	if err := s.db.First(&user, *shift.UserID).Error; err != nil {
+		return nil
+	}
+	return &user
+}
+
+// rotationDays is the length of a rotation shift in days, or 0 for unknown types
+func rotationDays(rotationType string) int {
+	switch rotationType {
+	case "daily":
+		return 1
+	case "weekly":
+		return 7
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+
+	"vigil/internal/database"
+)
+
+func TestResolveOnCallRotation(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+
+	// Participants are listed out of order; the rotation follows Position
+	participants := []database.OnCallParticipant{
+		{Position: 2, UserID: 20},
+		{Position: 3, UserID: 30},
+		{Position: 1, UserID: 10},
+	}
+
+	// Weekly handoffs at 09:00 New York time starting Monday 4 March 2024. Clocks spring
+	// forward on 10 March, so the second handoff is 167 hours after the first.
+	weekly := &database.OnCallSchedule{
+		Timezone:      "America/New_York",
+		RotationType:  "weekly",
+		RotationStart: time.Date(2024, 3, 4, 9, 0, 0, 0, newYork),
+		Participants:  participants,
+	}
+
+	// Daily handoffs at 09:00 New York time starting 1 November 2024. Clocks fall back on
+	// 3 November, so that day's handoff is 25 hours after the previous one.
+	daily := &database.OnCallSchedule{
+		Timezone:      "America/New_York",
+		RotationType:  "daily",
+		RotationStart: time.Date(2024, 11, 1, 9, 0, 0, 0, newYork),
+		Participants:  participants,
+	}
+
+	tests := []struct {
+		name      string
+		schedule  *database.OnCallSchedule
+		at        time.Time
+		wantUser  uint // 0 when nobody is on call
+		wantStart time.Time
+	}{
+		{name: "before the rotation starts", schedule: weekly, at: time.Date(2024, 3, 4, 13, 59, 0, 0, time.UTC)},
+		{name: "first shift", schedule: weekly, at: time.Date(2024, 3, 4, 14, 0, 0, 0, time.UTC), wantUser: 10, wantStart: time.Date(2024, 3, 4, 14, 0, 0, 0, time.UTC)},
+		{name: "just before the handoff after spring forward", schedule: weekly, at: time.Date(2024, 3, 11, 12, 59, 0, 0, time.UTC), wantUser: 10, wantStart: time.Date(2024, 3, 4, 14, 0, 0, 0, time.UTC)},
+		{name: "handoff at 09:00 local after spring forward", schedule: weekly, at: time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC), wantUser: 20, wantStart: time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC)},
+		{name: "rotation wraps around", schedule: weekly, at: time.Date(2024, 3, 25, 13, 0, 0, 0, time.UTC), wantUser: 10, wantStart: time.Date(2024, 3, 25, 13, 0, 0, 0, time.UTC)},
+		{name: "before fall back", schedule: daily, at: time.Date(2024, 11, 2, 13, 0, 0, 0, time.UTC), wantUser: 20, wantStart: time.Date(2024, 11, 2, 13, 0, 0, 0, time.UTC)},
+		{name: "an hour past the old handoff time after fall back", schedule: daily, at: time.Date(2024, 11, 3, 13, 30, 0, 0, time.UTC), wantUser: 20, wantStart: time.Date(2024, 11, 2, 13, 0, 0, 0, time.UTC)},
+		{name: "handoff at 09:00 local after fall back", schedule: daily, at: time.Date(2024, 11, 3, 14, 0, 0, 0, time.UTC), wantUser: 30, wantStart: time.Date(2024, 11, 3, 14, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shift := ResolveOnCall(tt.schedule, tt.at)
+			if tt.wantUser == 0 {
+				if shift.UserID != nil {
+					t.Fatalf("user %d is on call, want nobody", *shift.UserID)
+				}
+				return
+			}
+			if shift.UserID == nil || *shift.UserID != tt.wantUser {
+				t.Fatalf("on call = %v, want user %d", shift.UserID, tt.wantUser)
+			}
+			if shift.Override {
+				t.Error("rotation shift reported as an override")
+			}
+			if !shift.Start.Equal(tt.wantStart) {
+				t.Errorf("shift starts %v, want %v", shift.Start.UTC(), tt.wantStart)
+			}
+			if want := shift.Start.In(newYork).AddDate(0, 0, rotationDays(tt.schedule.RotationType)); !shift.End.Equal(want) {
+				t.Errorf("shift ends %v, want %v", shift.End.UTC(), want.UTC())
+			}
+		})
+	}
+}
+
+func TestResolveOnCallOverrides(t *testing.T) {
+	base := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	at := base.Add(30 * time.Hour)
+
+	override := func(userID uint, start, end time.Time, created time.Time) database.OnCallOverride {
+		return database.OnCallOverride{UserID: userID, StartsAt: start, EndsAt: end, CreatedAt: created}
+	}
+
+	tests := []struct {
+		name         string
+		overrides    []database.OnCallOverride
+		participants bool
+		wantUser     uint
+		wantOverride bool
+	}{
+		{name: "no overrides", participants: true, wantUser: 10},
+		{
+			name:         "active override wins over the rotation",
+			overrides:    []database.OnCallOverride{override(99, at.Add(-time.Hour), at.Add(time.Hour), base)},
+			participants: true, wantUser: 99, wantOverride: true,
+		},
+		{
+			name: "newest overlapping override wins",
+			overrides: []database.OnCallOverride{
+				override(98, at.Add(-2*time.Hour), at.Add(2*time.Hour), base.Add(time.Hour)),
+				override(99, at.Add(-time.Hour), at.Add(time.Hour), base.Add(2*time.Hour)),
+				override(97, at.Add(-3*time.Hour), at.Add(3*time.Hour), base),
+			},
+			participants: true, wantUser: 99, wantOverride: true,
+		},
+		{
+			name:         "ended override is ignored",
+			overrides:    []database.OnCallOverride{override(99, at.Add(-time.Hour), at, base)},
+			participants: true, wantUser: 10,
+		},
+		{
+			name:         "future override is ignored",
+			overrides:    []database.OnCallOverride{override(99, at.Add(time.Minute), at.Add(time.Hour), base)},
+			participants: true, wantUser: 10,
+		},
+		{
+			name:      "override applies without participants",
+			overrides: []database.OnCallOverride{override(99, at.Add(-time.Hour), at.Add(time.Hour), base)},
+			wantUser:  99, wantOverride: true,
+		},
+		{name: "nobody without participants or overrides"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := &database.OnCallSchedule{
+				Timezone:      "UTC",
+				RotationType:  "weekly",
+				RotationStart: base,
+				Overrides:     tt.overrides,
+			}
+			if tt.participants {
+				schedule.Participants = []database.OnCallParticipant{{Position: 1, UserID: 10}, {Position: 2, UserID: 20}}
+			}
+
+			shift := ResolveOnCall(schedule, at)
+			if tt.wantUser == 0 {
+				if shift.UserID != nil {
+					t.Fatalf("user %d is on call, want nobody", *shift.UserID)
+				}
+				return
+			}
+			if shift.UserID == nil || *shift.UserID != tt.wantUser {
+				t.Fatalf("on call = %v, want user %d", shift.UserID, tt.wantUser)
+			}
+			if shift.Override != tt.wantOverride {
+				t.Errorf("override = %v, want %v", shift.Override, tt.wantOverride)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package monitoring
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// postmortemLeadIn is how far before the first alert a postmortem window starts, so the
+// timeline shows what led up to it
+const postmortemLeadIn = 15 * time.Minute
+
+// maxTimelineChecks bounds how many checks of each monitor are scanned for a postmortem timeline
+const maxTimelineChecks = 10000
+
+// TimelineEntry is one event on a postmortem timeline
+type TimelineEntry struct {
+	At      time.Time `json:"at"`
+	Kind    string    `json:"kind"` // check, alert, alert_event, notification, incident_update
+	Message string    `json:"message"`
+}
+
+// PostmortemWindow returns the default timeline window for an incident or, when incident
+// is nil, an alert. Open incidents and alerts run until now.
+func PostmortemWindow(incident *database.Incident, alert *database.Alert) (time.Time, time.Time) {
+	end := time.Now()
+	if incident != nil {
+		if incident.ResolvedAt != nil {
+			end = *incident.ResolvedAt
+		}
+		return incident.StartedAt.Add(-postmortemLeadIn), end
+	}
+
+	if alert.ResolvedAt != nil {
+		end = *alert.ResolvedAt
+	}
+	return alert.CreatedAt.Add(-postmortemLeadIn), end
+}
+
+// PostmortemTimeline builds a postmortem's timeline from the monitor status changes,
+// alerts, alert events, notifications and incident updates in its window
+func (s *Service) PostmortemTimeline(postmortem *database.Postmortem) ([]TimelineEntry, error) {
+	start, end := postmortem.WindowStart, postmortem.WindowEnd
+
+	var alerts []database.Alert
+	query := s.db.Preload("Monitor").Preload("Webhook")
+	switch {
+	case postmortem.IncidentID != nil:
+		query = query.Where("incident_id = ?", *postmortem.IncidentID)
+	case postmortem.AlertID != nil:
+		query = query.Where("id = ?", *postmortem.AlertID)
+	default:
+		return nil, nil
+	}
+	if err := query.Order("created_at").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+
+	var timeline []TimelineEntry
+	add := func(at time.Time, kind, message string) {
+		if at.Before(start) || at.After(end) {
+			return
+		}
+		timeline = append(timeline, TimelineEntry{At: at, Kind: kind, Message: message})
+	}
+
+	alertIDs := make([]uint, 0, len(alerts))
+	monitorIDs := make(map[uint]string)
+	for _, alert := range alerts {
+		alertIDs = append(alertIDs, alert.ID)
+		source := alertSource(&alert)
+		if alert.Monitor != nil {
+			monitorIDs[alert.Monitor.ID] = alert.Monitor.Name
+		}
+
+		add(alert.CreatedAt, "alert", fmt.Sprintf("%s alert %q raised on %s: %s", alert.Severity, alert.Type, source, alert.Message))
+		if alert.ResolvedAt != nil {
+			add(*alert.ResolvedAt, "alert", fmt.Sprintf("Alert %q on %s resolved", alert.Type, source))
+		}
+	}
+
+	if len(alertIDs) > 0 {
+		// Creation and resolution are already on the timeline from the alerts themselves
+		var events []database.AlertEvent
+		if err := s.db.Preload("Actor").Preload("Assignee").
+			Where("alert_id IN ? AND type NOT IN ? AND created_at BETWEEN ? AND ?", alertIDs, []string{"created", "resolved"}, start, end).
+			Find(&events).Error; err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			add(event.CreatedAt, "alert_event", alertEventSummary(&event))
+		}
+
+		var notifications []database.AlertNotification
+		if err := s.db.Preload("NotificationChannel").Preload("User").
+			Where("alert_id IN ? AND sent_at BETWEEN ? AND ?", alertIDs, start, end).
+			Find(&notifications).Error; err != nil {
+			return nil, err
+		}
+		for _, notification := range notifications {
+			add(notification.SentAt, "notification", notificationSummary(&notification))
+		}
+	}
+
+	if postmortem.IncidentID != nil {
+		var updates []database.IncidentUpdate
+		if err := s.db.Preload("Author").
+			Where("incident_id = ? AND created_at BETWEEN ? AND ?", *postmortem.IncidentID, start, end).
+			Find(&updates).Error; err != nil {
+			return nil, err
+		}
+		for _, update := range updates {
+			message := fmt.Sprintf("Incident status: %s", update.Status)
+			if update.Author != nil {
+				message += " (" + update.Author.Email + ")"
+			}
+			if update.Message != "" {
+				message += ": " + update.Message
+			}
+			add(update.CreatedAt, "incident_update", message)
+		}
+	}
+
+	ids := make([]uint, 0, len(monitorIDs))
+	for id := range monitorIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Checks are limited per monitor so a frequently checked monitor cannot crowd out the others
+	for _, id := range ids {
+		var checks []database.MonitorCheck
+		if err := s.db.Select("monitor_id", "status", "status_code", "error_message", "checked_at").
+			Where("monitor_id = ? AND checked_at BETWEEN ? AND ?", id, start, end).
+			Order("checked_at").
+			Limit(maxTimelineChecks).
+			Find(&checks).Error; err != nil {
+			return nil, err
+		}
+
+		// Only status changes are interesting; the first check shows the starting state
+		previous := ""
+		for i, check := range checks {
+			if i > 0 && check.Status == previous {
+				continue
+			}
+			previous = check.Status
+
+			message := fmt.Sprintf("%s is %s", monitorIDs[id], check.Status)
+			if check.ErrorMessage != "" {
+				message += ": " + check.ErrorMessage
+			}
+			add(check.CheckedAt, "check", message)
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].At.Before(timeline[j].At) })
+	return timeline, nil
+}
+
+// RenderPostmortemMarkdown renders a postmortem, with its timeline and action items, as Markdown
+func RenderPostmortemMarkdown(postmortem *database.Postmortem, timeline []TimelineEntry) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", postmortem.Title)
+	fmt.Fprintf(&b, "- **Status:** %s\n", postmortem.Status)
+	if postmortem.IncidentID != nil {
+		fmt.Fprintf(&b, "- **Incident:** #%d\n", *postmortem.IncidentID)
+	}
+	if postmortem.AlertID != nil {
+		fmt.Fprintf(&b, "- **Alert:** #%d\n", *postmortem.AlertID)
+	}
+	if postmortem.Author != nil {
+		fmt.Fprintf(&b, "- **Author:** %s\n", postmortem.Author.Email)
+	}
+	fmt.Fprintf(&b, "- **Window:** %s to %s\n\n",
+		postmortem.WindowStart.UTC().Format(time.RFC3339), postmortem.WindowEnd.UTC().Format(time.RFC3339))
+
+	sections := []struct{ heading, body string }{
+		{"Summary", postmortem.Summary},
+		{"Impact", postmortem.Impact},
+		{"Root cause", postmortem.RootCause},
+		{"Resolution", postmortem.Resolution},
+		{"Lessons learned", postmortem.LessonsLearned},
+	}
+	for _, section := range sections {
+		body := strings.TrimSpace(section.body)
+		if body == "" {
+			body = "_Not written yet._"
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", section.heading, body)
+	}
+
+	b.WriteString("## Timeline\n\n")
+	if len(timeline) == 0 {
+		b.WriteString("_No events in the window._\n\n")
+	} else {
+		b.WriteString("| Time (UTC) | Type | Event |\n|---|---|---|\n")
+		for _, entry := range timeline {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", entry.At.UTC().Format("2006-01-02 15:04:05"), entry.Kind, markdownCell(entry.Message))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Action items\n\n")
+	if len(postmortem.ActionItems) == 0 {
+		b.WriteString("_None._\n")
+	}
+	for _, item := range postmortem.ActionItems {
+		check := " "
+		if item.Status == "done" {
+			check = "x"
+		}
+		line := fmt.Sprintf("- [%s] %s", check, item.Description)
+		if item.Owner != nil {
+			line += " — " + item.Owner.Email
+		}
+		if item.DueDate != nil {
+			line += " (due " + item.DueDate.Format("2006-01-02") + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+// alertSource names the monitor or webhook an alert was raised for
+func alertSource(alert *database.Alert) string {
+	switch {
+	case alert.Monitor != nil:
+		return "monitor " + alert.Monitor.Name
+	case alert.Webhook != nil:
+		return "webhook " + alert.Webhook.Name
+	}
+	return "a deleted source"
+}
+
+// alertEventSummary describes an alert timeline event in one line
+func alertEventSummary(event *database.AlertEvent) string {
+	message := fmt.Sprintf("Alert #%d %s", event.AlertID, event.Type)
+	if event.Actor != nil {
+		message += " by " + event.Actor.Email
+	}
+	if event.Assignee != nil {
+		message += " to " + event.Assignee.Email
+	}
+	if event.Message != "" {
+		message += ": " + event.Message
+	}
+	return message
+}
+
+// notificationSummary describes a sent notification in one line
+func notificationSummary(notification *database.AlertNotification) string {
+	target := "a deleted channel"
+	switch {
+	case notification.NotificationChannel != nil:
+		target = fmt.Sprintf("%s channel #%d", notification.NotificationChannel.Type, notification.NotificationChannel.ID)
+	case notification.User != nil:
+		target = notification.User.Email
+	}
+
+	message := fmt.Sprintf("Alert #%d notified %s: %s", notification.AlertID, target, notification.Status)
+	if notification.EscalationLevel > 0 {
+		message += fmt.Sprintf(" (escalation level %d)", notification.EscalationLevel)
+	}
+	if notification.ReminderNumber > 0 {
+		message += fmt.Sprintf(" (reminder %d)", notification.ReminderNumber)
+	}
+	if notification.ErrorMessage != "" {
+		message += " (" + notification.ErrorMessage + ")"
+	}
+	return message
+}
+
+// markdownCell makes text safe to put in a Markdown table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,177 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// reminderSchedule is how often open alerts are checked for a due reminder
+const reminderSchedule = "30 * * * * *"
+
+// maxReminders bounds how many reminders a channel or policy may send per alert
+const maxReminders = 100
+
+// reminderState is when a destination was last notified about an alert and how many
+// reminders it has had
+type reminderState struct {
+	NotificationChannelID uint // set when grouped by channel
+	LastSentAt            *time.Time
+	Reminders             int
+}
+
+// ValidateReminders checks a reminder interval and the maximum number of reminders
+func ValidateReminders(intervalMinutes, max int) error {
+	if intervalMinutes < 0 || intervalMinutes > 7*24*60 {
+		return fmt.Errorf("reminder_interval_minutes must be between 0 and %d", 7*24*60)
+	}
+	if intervalMinutes > 0 && (max < 1 || max > maxReminders) {
+		return fmt.Errorf("max_reminders must be between 1 and %d", maxReminders)
+	}
+	return nil
+}
+
+// processDueReminders re-sends notifications for alerts still unresolved and unacknowledged.
+// Alerts under an escalation policy follow the policy's reminder settings; other alerts
+// follow those of each channel they were sent to.
+func (s *Service) processDueReminders() {
+	// Only alerts that could get a reminder are loaded: those under a policy with reminders
+	// on, and those without a policy sent to an active channel with reminders on
+	var alerts []database.Alert
+	if err := s.db.Where("resolved_at IS NULL AND acknowledged_at IS NULL").
+		Where(`(escalation_policy_id IS NOT NULL AND escalation_level > 0 AND EXISTS (
+				SELECT 1 FROM escalation_policies
+				WHERE escalation_policies.id = alerts.escalation_policy_id
+				AND escalation_policies.reminder_interval_minutes > 0
+			)) OR (escalation_policy_id IS NULL AND EXISTS (
+				SELECT 1 FROM alert_notifications
+				JOIN notification_channels ON notification_channels.id = alert_notifications.notification_channel_id
+				WHERE alert_notifications.alert_id = alerts.id
+				AND notification_channels.is_active = ?
+				AND notification_channels.reminder_interval_minutes > 0
+			))`, true).
+		Order("created_at").
+		Find(&alerts).Error; err != nil {
+		s.log.Errorf("Failed to load open alerts for reminders: %v", err)
+		return
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+
+		// Reminders wait, like escalations, until a monitor's maintenance is over
+		if alert.MonitorID != nil {
+			var monitor database.Monitor
+			if err := s.db.First(&monitor, *alert.MonitorID).Error; err == nil {
+				if _, inMaintenance := s.maintenanceEnd(&monitor); inMaintenance {
+					continue
+				}
+			}
+		}
+
+		if alert.EscalationPolicyID != nil {
+			s.remindPolicy(alert)
+		} else {
+			s.remindChannels(alert)
+		}
+	}
+}
+
+// remindChannels re-notifies each reminder-enabled channel an alert was sent to once its
+// reminder interval has passed
+func (s *Service) remindChannels(alert *database.Alert) {
+	var states []reminderState
+	if err := s.db.Model(&database.AlertNotification{}).
+		Select("notification_channel_id, MAX(sent_at) AS last_sent_at, COALESCE(MAX(reminder_number), 0) AS reminders").
+		Where("alert_id = ? AND notification_channel_id IS NOT NULL", alert.ID).
+		Group("notification_channel_id").
+		Scan(&states).Error; err != nil {
+		s.log.Errorf("Failed to load notifications of alert %d: %v", alert.ID, err)
+		return
+	}
+
+	for _, state := range states {
+		var channel database.NotificationChannel
+		if err := s.db.Where("id = ? AND is_active = ?", state.NotificationChannelID, true).First(&channel).Error; err != nil {
+			continue
+		}
+
+		if !reminderDue(state, channel.ReminderIntervalMinutes, channel.MaxReminders) {
+			continue
+		}
+
+		number := state.Reminders + 1
+		reminder := reminderCopy(alert, number, channel.MaxReminders)
+		if !s.notifyChannel(&reminder, &channel, 0, number) {
+			continue
+		}
+		s.recordAlertEvent(alert.ID, "reminded", fmt.Sprintf("Reminder %d of %d sent to %s channel #%d", number, channel.MaxReminders, channel.Type, channel.ID))
+	}
+}
+
+// remindPolicy re-notifies the escalation level an alert has reached once the policy's
+// reminder interval has passed since that level was last notified
+func (s *Service) remindPolicy(alert *database.Alert) {
+	if alert.EscalationLevel == 0 {
+		return
+	}
+
+	var policy database.EscalationPolicy
+	if err := s.db.First(&policy, *alert.EscalationPolicyID).Error; err != nil {
+		return
+	}
+	if policy.ReminderIntervalMinutes == 0 {
+		return
+	}
+
+	var state reminderState
+	if err := s.db.Model(&database.AlertNotification{}).
+		Select("MAX(sent_at) AS last_sent_at, COALESCE(MAX(reminder_number), 0) AS reminders").
+		Where("alert_id = ? AND escalation_level = ?", alert.ID, alert.EscalationLevel).
+		Scan(&state).Error; err != nil {
+		s.log.Errorf("Failed to load notifications of alert %d: %v", alert.ID, err)
+		return
+	}
+
+	if !reminderDue(state, policy.ReminderIntervalMinutes, policy.MaxReminders) {
+		return
+	}
+
+	level := s.escalationLevel(policy.ID, alert.EscalationLevel)
+	if level == nil {
+		return
+	}
+
+	number := state.Reminders + 1
+	reminder := reminderCopy(alert, number, policy.MaxReminders)
+	notified := false
+	for i := range level.Targets {
+		if s.notifyEscalationTarget(&reminder, &level.Targets[i], alert.EscalationLevel, number) {
+			notified = true
+		}
+	}
+
+	// Without a recorded notification the reminder is not counted and is retried on the
+	// next pass, so it is not logged as sent either
+	if !notified {
+		return
+	}
+
+	s.recordAlertEvent(alert.ID, "reminded", fmt.Sprintf("Reminder %d of %d sent to level %d", number, policy.MaxReminders, alert.EscalationLevel))
+}
+
+// reminderDue reports whether another reminder should go out
+func reminderDue(state reminderState, intervalMinutes, max int) bool {
+	if intervalMinutes <= 0 || state.LastSentAt == nil || state.Reminders >= max {
+		return false
+	}
+	return !time.Now().Before(state.LastSentAt.Add(time.Duration(intervalMinutes) * time.Minute))
+}
+
+// reminderCopy returns a copy of an alert whose message carries the reminder counter
+func reminderCopy(alert *database.Alert, number, max int) database.Alert {
+	reminder := *alert
+	reminder.Message = fmt.Sprintf("Reminder %d of %d: %s", number, max, alert.Message)
+	return reminder
+}
@@ -0,0 +1,124 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"vigil/internal/database"
+)
+
+// redactedValue replaces resolved secret values in stored check output
+const redactedValue = "[REDACTED]"
+
+// secretPlaceholder matches {{secrets.NAME}} references in monitor configuration
+var secretPlaceholder = regexp.MustCompile(`\{\{\s*secrets\.([A-Za-z0-9_]+)\s*\}\}`)
+
+// HasSecretPlaceholder reports whether any of the values reference an organization secret
+func HasSecretPlaceholder(values ...string) bool {
+	for _, value := range values {
+		if secretPlaceholder.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSecrets returns a copy of the monitor with secret placeholders replaced by their
+// values, along with the values that were substituted so they can be redacted from output
+func (s *Service) resolveSecrets(monitor *database.Monitor) (*database.Monitor, []string, error) {
+	resolved := *monitor
+
+	fields := []*string{
+		&resolved.URL,
+		&resolved.RequestBody,
+		&resolved.AuthUsername,
+		&resolved.AuthPassword,
+		&resolved.AuthToken,
+		&resolved.ClientCertificate,
+		&resolved.ClientKey,
+		&resolved.CACertificates,
+		&resolved.ProxyURL,
+	}
+
+	// Collect every referenced secret name so they can be loaded in one query
+	names := make(map[string]bool)
+	for _, field := range append(fields, &resolved.CustomHeaders) {
+		for _, match := range secretPlaceholder.FindAllStringSubmatch(*field, -1) {
+			names[match[1]] = true
+		}
+	}
+
+	if len(names) == 0 {
+		return &resolved, nil, nil
+	}
+
+	nameList := make([]string, 0, len(names))
+	for name := range names {
+		nameList = append(nameList, name)
+	}
+
+	var secrets []database.OrganizationSecret
+	if err := s.db.Where("organization_id = ? AND name IN ?", monitor.OrganizationID, nameList).
+		Find(&secrets).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		values[secret.Name] = secret.Value
+	}
+
+	for name := range names {
+		if _, ok := values[name]; !ok {
+			return nil, nil, fmt.Errorf("unknown secret %q", name)
+		}
+	}
+
+	replace := func(input string) string {
+		return secretPlaceholder.ReplaceAllStringFunc(input, func(placeholder string) string {
+			return values[secretPlaceholder.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+
+	for _, field := range fields {
+		*field = replace(*field)
+	}
+
+	// Headers are a JSON object, so substitute inside the decoded values to keep it valid
+	if resolved.CustomHeaders != "" {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(resolved.CustomHeaders), &headers); err == nil {
+			for key, value := range headers {
+				headers[key] = replace(value)
+			}
+			encoded, _ := json.Marshal(headers)
+			resolved.CustomHeaders = string(encoded)
+		}
+	}
+
+	used := make([]string, 0, len(values))
+	for _, value := range values {
+		used = append(used, value)
+	}
+
+	return &resolved, used, nil
+}
+
+// redactSecrets replaces any resolved secret values in text
+func redactSecrets(text string, values []string) string {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		text = strings.ReplaceAll(text, value, redactedValue)
+
+		// Secrets used in URLs show up escaped in client errors
+		if escaped := url.QueryEscape(value); escaped != value {
+			text = strings.ReplaceAll(text, escaped, redactedValue)
+		}
+	}
+	return text
+}
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -14,6 +17,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"vigil/internal/database"
+	"vigil/internal/services"
 )
 
 // Service handles all monitoring operations
@@ -22,15 +26,22 @@ type Service struct {
 	redis *redis.Client
 	cron  *cron.Cron
 	log   *logrus.Logger
+	email *services.EmailService
+
+	// entries maps monitor IDs to their cron entries
+	entries   map[uint]cron.EntryID
+	entriesMu sync.Mutex
 }
 
 // NewService creates a new monitoring service
 func NewService(db *database.DB, redis *redis.Client) *Service {
 	return &Service{
-		db:    db,
-		redis: redis,
-		cron:  cron.New(cron.WithSeconds()),
-		log:   logrus.New(),
+		db:      db,
+		redis:   redis,
+		cron:    cron.New(cron.WithSeconds()),
+		log:     logrus.New(),
+		email:   services.NewEmailService(),
+		entries: make(map[uint]cron.EntryID),
 	}
 }
 
@@ -41,6 +52,31 @@ func (s *Service) StartScheduler() {
 
 	// Schedule existing monitors
 	s.scheduleExistingMonitors()
+
+	// Schedule background workers
+	s.scheduleWorkers()
+}
+
+// scheduleWorkers schedules the periodic background jobs
+func (s *Service) scheduleWorkers() {
+	// A delivery batch can outlast its tick, so skip ticks while the previous batch runs
+	skipIfRunning := cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger))
+
+	if _, err := s.cron.AddJob(webhookDeliverySchedule, skipIfRunning.Then(cron.FuncJob(s.processDueWebhookDeliveries))); err != nil {
+		s.log.Errorf("Failed to schedule webhook delivery worker: %v", err)
+	}
+	if _, err := s.cron.AddJob(webhookDeliverySchedule, skipIfRunning.Then(cron.FuncJob(s.processDueWebhookDeliveryTargets))); err != nil {
+		s.log.Errorf("Failed to schedule webhook destination worker: %v", err)
+	}
+	if _, err := s.cron.AddFunc(webhookCadenceSchedule, s.checkSilentWebhooks); err != nil {
+		s.log.Errorf("Failed to schedule webhook cadence check: %v", err)
+	}
+	if _, err := s.cron.AddFunc(escalationSchedule, s.processDueEscalations); err != nil {
+		s.log.Errorf("Failed to schedule escalation worker: %v", err)
+	}
+	if _, err := s.cron.AddFunc(reminderSchedule, s.processDueReminders); err != nil {
+		s.log.Errorf("Failed to schedule reminder worker: %v", err)
+	}
 }
 
 // StopScheduler stops the monitoring scheduler
@@ -64,13 +100,19 @@ func (s *Service) scheduleExistingMonitors() {
 
 // scheduleMonitor schedules a single monitor
 func (s *Service) scheduleMonitor(monitor *database.Monitor) {
+	s.entriesMu.Lock()
+	defer s.entriesMu.Unlock()
+
 	// Remove existing schedule if any
-	s.cron.Remove(cron.EntryID(monitor.ID))
+	if entryID, ok := s.entries[monitor.ID]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, monitor.ID)
+	}
 
 	// Calculate cron expression based on interval
 	cronExpr := s.intervalToCron(monitor.IntervalSeconds)
 
-	_, err := s.cron.AddFunc(cronExpr, func() {
+	entryID, err := s.cron.AddFunc(cronExpr, func() {
 		s.checkMonitor(monitor)
 	})
 
@@ -78,6 +120,7 @@ func (s *Service) scheduleMonitor(monitor *database.Monitor) {
 		s.log.Errorf("Failed to schedule monitor %d: %v", monitor.ID, err)
 		return
 	}
+	s.entries[monitor.ID] = entryID
 
 	s.log.Infof("Scheduled monitor %d (%s) with interval %ds", monitor.ID, monitor.Name, monitor.IntervalSeconds)
 }
@@ -116,24 +159,47 @@ func (s *Service) checkMonitor(monitor *database.Monitor) {
 	var errorMessage string
 	var responseBody string
 
-	switch monitor.Type {
-	case "http":
-		status, statusCode, errorMessage, responseBody = s.checkHTTP(monitor)
-	case "ssl":
-		status, errorMessage = s.checkSSL(monitor)
-		statusCode = 0 // SSL checks don't have HTTP status codes
-	case "webhook":
-		status, statusCode, errorMessage = s.checkWebhook(monitor)
-		responseBody = "" // Webhook checks don't have response body
-	default:
-		status = "unknown"
-		statusCode = 0
-		errorMessage = fmt.Sprintf("Unknown monitor type: %s", monitor.Type)
-		responseBody = ""
+	// Secrets are only resolved for the duration of the check
+	resolved, secretValues, err := s.resolveSecrets(monitor)
+	if err != nil {
+		status = "down"
+		errorMessage = err.Error()
+	} else {
+		switch resolved.Type {
+		case "http":
+			status, statusCode, errorMessage, responseBody = s.checkHTTP(resolved)
+		case "ssl":
+			status, errorMessage = s.checkSSL(resolved)
+			statusCode = 0 // SSL checks don't have HTTP status codes
+		case "webhook":
+			status, statusCode, errorMessage = s.checkWebhook(resolved)
+			responseBody = "" // Webhook checks don't have response body
+		default:
+			status = "unknown"
+			statusCode = 0
+			errorMessage = fmt.Sprintf("Unknown monitor type: %s", resolved.Type)
+			responseBody = ""
+		}
 	}
 
 	responseTime := int(time.Since(start).Milliseconds())
 
+	// Checks keep running during maintenance, but are left out of uptime and raise no alerts
+	_, inMaintenance := s.maintenanceEnd(monitor)
+
+	// A failure while a parent is down is put down to the parent, which raises the alert
+	var reason string
+	if status == "down" {
+		if parent, ok := s.downParent(monitor); ok {
+			reason = "dependency_down"
+			s.log.Debugf("Monitor %d is down while its parent %d (%s) is down", monitor.ID, parent.ID, parent.Name)
+		}
+	}
+
+	// Never store resolved secret values in check output
+	errorMessage = redactSecrets(errorMessage, secretValues)
+	responseBody = redactSecrets(responseBody, secretValues)
+
 	// Create monitor check record
 	check := database.MonitorCheck{
 		MonitorID:    monitor.ID,
@@ -143,6 +209,9 @@ func (s *Service) checkMonitor(monitor *database.Monitor) {
 		ErrorMessage: errorMessage,
 		ResponseBody: responseBody,
 		CheckedAt:    time.Now(),
+
+		InMaintenance: inMaintenance,
+		Reason:        reason,
 	}
 
 	if err := s.db.Create(&check).Error; err != nil {
@@ -150,6 +219,23 @@ func (s *Service) checkMonitor(monitor *database.Monitor) {
 		return
 	}
 
+	if inMaintenance || reason == "dependency_down" {
+		if status == "up" {
+			s.resolveAlerts(monitor.ID, "down")
+		}
+		s.cacheMonitorStatus(monitor.ID, status, responseTime)
+		return
+	}
+
+	// User-defined rules are evaluated on every check, alongside the built-in down alert
+	s.evaluateAlertRules(monitor)
+
+	// While a monitor is flapping its single flapping alert stands in for up/down alerts
+	if s.updateFlapState(monitor) {
+		s.cacheMonitorStatus(monitor.ID, status, responseTime)
+		return
+	}
+
 	// Check if we need to create an alert
 	if status == "down" {
 		s.createAlert(monitor, "down", fmt.Sprintf("Monitor %s is down", monitor.Name), "high")
@@ -164,15 +250,34 @@ func (s *Service) checkMonitor(monitor *database.Monitor) {
 
 // checkHTTP performs an HTTP check
 func (s *Service) checkHTTP(monitor *database.Monitor) (status string, statusCode int, errorMessage string, responseBody string) {
-	client := &http.Client{
-		Timeout: time.Duration(monitor.TimeoutSeconds) * time.Second,
+	client, err := newHTTPClient(monitor)
+	if err != nil {
+		return "down", 0, err.Error(), ""
 	}
+	defer client.CloseIdleConnections()
 
-	req, err := http.NewRequest("GET", monitor.URL, nil)
+	method := monitor.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var requestBody io.Reader
+	if monitor.RequestBody != "" {
+		requestBody = strings.NewReader(monitor.RequestBody)
+	}
+
+	req, err := http.NewRequest(method, monitor.URL, requestBody)
 	if err != nil {
 		return "down", 0, err.Error(), ""
 	}
 
+	switch monitor.AuthType {
+	case "basic":
+		req.SetBasicAuth(monitor.AuthUsername, monitor.AuthPassword)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+monitor.AuthToken)
+	}
+
 	// Add custom headers if specified
 	if monitor.CustomHeaders != "" {
 		var headers map[string]string
@@ -201,12 +306,35 @@ func (s *Service) checkHTTP(monitor *database.Monitor) (status string, statusCod
 
 // checkSSL performs an SSL certificate check
 func (s *Service) checkSSL(monitor *database.Monitor) (status, errorMessage string) {
-	conn, err := tls.Dial("tcp", monitor.URL, nil)
+	tlsConfig, err := buildTLSConfig(monitor)
+	if err != nil {
+		return "down", err.Error()
+	}
+	// Verifying the certificate is the point of an SSL check, so never skip it here
+	tlsConfig.InsecureSkipVerify = false
+
+	host, _, err := net.SplitHostPort(monitor.URL)
+	if err != nil {
+		host = monitor.URL
+	}
+	tlsConfig.ServerName = host
+
+	timeout := time.Duration(monitor.TimeoutSeconds) * time.Second
+	rawConn, err := dialMonitor(monitor, monitor.URL, timeout)
 	if err != nil {
 		return "down", err.Error()
 	}
+
+	conn := tls.Client(rawConn, tlsConfig)
 	defer conn.Close()
 
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if err := conn.Handshake(); err != nil {
+		return "down", err.Error()
+	}
+
 	cert := conn.ConnectionState().PeerCertificates[0]
 	expiry := cert.NotAfter
 	now := time.Now()
@@ -227,81 +355,178 @@ func (s *Service) checkWebhook(monitor *database.Monitor) (status string, status
 	return status, statusCode, errorMessage
 }
 
-// createAlert creates a new alert
+// createAlert creates a new alert for a monitor
 func (s *Service) createAlert(monitor *database.Monitor, alertType, message, severity string) {
-	// Check if there's already an active alert for this monitor and type
-	var existingAlert database.Alert
-	err := s.db.Where("monitor_id = ? AND type = ? AND resolved_at IS NULL", monitor.ID, alertType).First(&existingAlert).Error
-
-	if err == nil {
-		// Alert already exists, don't create duplicate
+	if _, inMaintenance := s.maintenanceEnd(monitor); inMaintenance {
 		return
 	}
 
-	alert := database.Alert{
-		MonitorID: monitor.ID,
+	monitorID := monitor.ID
+	s.raiseAlert(&database.Alert{
+		MonitorID:          &monitorID,
+		Type:               alertType,
+		Message:            message,
+		Severity:           severity,
+		EscalationPolicyID: monitor.EscalationPolicyID,
+	}, monitor.OrganizationID)
+}
+
+// createWebhookAlert creates a new alert for a webhook
+func (s *Service) createWebhookAlert(webhook *database.Webhook, alertType, message, severity string) {
+	webhookID := webhook.ID
+	s.raiseAlert(&database.Alert{
+		WebhookID: &webhookID,
 		Type:      alertType,
 		Message:   message,
 		Severity:  severity,
-		CreatedAt: time.Now(),
+	}, webhook.OrganizationID)
+}
+
+// raiseAlert stores an alert unless one of the same type is already active for its source
+func (s *Service) raiseAlert(alert *database.Alert, organizationID uint) {
+	// Check if there's already an active alert for this source and type
+	query := s.db.Where("type = ? AND resolved_at IS NULL", alert.Type)
+	if alert.MonitorID != nil {
+		query = query.Where("monitor_id = ?", *alert.MonitorID)
+	} else {
+		query = query.Where("webhook_id = ?", *alert.WebhookID)
+	}
+	if alert.AlertRuleID != nil {
+		query = query.Where("alert_rule_id = ?", *alert.AlertRuleID)
+	}
+	if alert.WebhookDestinationID != nil {
+		query = query.Where("webhook_destination_id = ?", *alert.WebhookDestinationID)
+	}
+
+	var existingAlert database.Alert
+	if err := query.First(&existingAlert).Error; err == nil {
+		// Alert already exists, don't create duplicate
+		return
+	}
+
+	alert.CreatedAt = time.Now()
+
+	// Alerts joining an open incident are covered by the notification its first alert sent
+	incident, opened := s.groupAlert(alert, organizationID)
+	if incident != nil {
+		if opened {
+			if err := s.db.Create(incident).Error; err != nil {
+				s.log.Errorf("Failed to open incident: %v", err)
+				incident = nil
+			}
+		} else {
+			alert.EscalationPolicyID = nil
+		}
+	}
+	if incident != nil {
+		alert.IncidentID = &incident.ID
 	}
 
-	if err := s.db.Create(&alert).Error; err != nil {
+	// Alerts under an escalation policy are notified level by level instead of all at once
+	var firstLevel *database.EscalationLevel
+	if alert.EscalationPolicyID != nil {
+		firstLevel = s.escalationLevel(*alert.EscalationPolicyID, 1)
+		if firstLevel == nil {
+			alert.EscalationPolicyID = nil
+		} else {
+			next := alert.CreatedAt.Add(time.Duration(firstLevel.DelayMinutes) * time.Minute)
+			alert.NextEscalationAt = &next
+		}
+	}
+
+	if err := s.db.Create(alert).Error; err != nil {
 		s.log.Errorf("Failed to create alert: %v", err)
 		return
 	}
+	s.recordAlertEvent(alert.ID, "created", alert.Message)
+
+	if incident != nil && !opened {
+		s.joinIncident(incident, alert)
+		return
+	}
 
-	// Send notifications
-	s.sendNotifications(&alert)
+	if firstLevel == nil {
+		s.sendNotifications(alert, organizationID)
+	} else if firstLevel.DelayMinutes == 0 {
+		s.escalateAlert(alert.ID)
+	}
 }
 
 // resolveAlerts resolves alerts for a monitor
 func (s *Service) resolveAlerts(monitorID uint, alertType string) {
+	s.resolveAlertsWhere("monitor_id = ? AND type = ? AND resolved_at IS NULL", monitorID, alertType)
+}
+
+// resolveWebhookAlerts resolves alerts for a webhook
+func (s *Service) resolveWebhookAlerts(webhookID uint, alertType string) {
+	s.resolveAlertsWhere("webhook_id = ? AND type = ? AND resolved_at IS NULL", webhookID, alertType)
+}
+
+// resolveAlertsWhere resolves the open alerts matching a condition and notes it on their timelines
+func (s *Service) resolveAlertsWhere(condition string, args ...interface{}) {
+	var alertIDs []uint
+	if err := s.db.Model(&database.Alert{}).Where(condition, args...).Pluck("id", &alertIDs).Error; err != nil {
+		s.log.Errorf("Failed to find alerts to resolve: %v", err)
+		return
+	}
+
+	if len(alertIDs) == 0 {
+		return
+	}
+
 	now := time.Now()
 	if err := s.db.Model(&database.Alert{}).
-		Where("monitor_id = ? AND type = ? AND resolved_at IS NULL", monitorID, alertType).
+		Where("id IN ? AND resolved_at IS NULL", alertIDs).
 		Update("resolved_at", now).Error; err != nil {
 		s.log.Errorf("Failed to resolve alerts: %v", err)
+		return
+	}
+
+	for _, alertID := range alertIDs {
+		s.recordAlertEvent(alertID, "resolved", "Resolved automatically after recovery")
+	}
+}
+
+// recordAlertEvent adds an event raised by Vigil to an alert's timeline
+func (s *Service) recordAlertEvent(alertID uint, eventType, message string) {
+	event := database.AlertEvent{
+		AlertID:   alertID,
+		Type:      eventType,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		s.log.Errorf("Failed to record %s event for alert %d: %v", eventType, alertID, err)
 	}
 }
 
-// sendNotifications sends notifications for an alert
-func (s *Service) sendNotifications(alert *database.Alert) {
-	// Get notification channels for the organization
+// sendNotifications notifies every active channel of the organization about an alert
+func (s *Service) sendNotifications(alert *database.Alert, organizationID uint) {
 	var channels []database.NotificationChannel
-	if err := s.db.Where("organization_id = ? AND is_active = ?", alert.Monitor.OrganizationID, true).Find(&channels).Error; err != nil {
+	if err := s.db.Where("organization_id = ? AND is_active = ?", organizationID, true).Find(&channels).Error; err != nil {
 		s.log.Errorf("Failed to get notification channels: %v", err)
 		return
 	}
 
-	for _, channel := range channels {
-		notification := database.AlertNotification{
-			AlertID:               alert.ID,
-			NotificationChannelID: channel.ID,
-			SentAt:                time.Now(),
-			Status:                "pending",
-		}
-
-		if err := s.db.Create(&notification).Error; err != nil {
-			s.log.Errorf("Failed to create notification: %v", err)
-			continue
-		}
-
-		// Send notification asynchronously
-		go s.sendNotification(&notification, &channel, alert)
+	for i := range channels {
+		s.notifyChannel(alert, &channels[i], 0, 0)
 	}
 }
 
-// sendNotification sends a single notification
-func (s *Service) sendNotification(notification *database.AlertNotification, channel *database.NotificationChannel, alert *database.Alert) {
-	// This would implement the actual notification sending logic
-	// For now, we'll just mark it as sent
-	notification.Status = "sent"
-	notification.SentAt = time.Now()
-
-	if err := s.db.Save(notification).Error; err != nil {
-		s.log.Errorf("Failed to update notification status: %v", err)
+// alertOrganizationID returns the organization of an alert's monitor or webhook, or 0
+// when its source no longer exists
+func (s *Service) alertOrganizationID(alert *database.Alert) uint {
+	var organizationIDs []uint
+	switch {
+	case alert.MonitorID != nil:
+		s.db.Model(&database.Monitor{}).Where("id = ?", *alert.MonitorID).Pluck("organization_id", &organizationIDs)
+	case alert.WebhookID != nil:
+		s.db.Model(&database.Webhook{}).Where("id = ?", *alert.WebhookID).Pluck("organization_id", &organizationIDs)
+	}
+	if len(organizationIDs) == 0 {
+		return 0
 	}
+	return organizationIDs[0]
 }
 
 // cacheMonitorStatus caches the latest monitor status
@@ -0,0 +1,214 @@
+package monitoring
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	"vigil/internal/database"
+)
+
+// ValidateTransportConfig checks that a monitor's TLS material and proxy URL are usable
+func ValidateTransportConfig(monitor *database.Monitor) error {
+	// Values that reference secrets can only be checked once resolved at check time, so
+	// only those are skipped and the rest are still validated
+	resolved := *monitor
+	if HasSecretPlaceholder(resolved.CACertificates) {
+		resolved.CACertificates = ""
+	}
+	if HasSecretPlaceholder(resolved.ClientCertificate, resolved.ClientKey) {
+		resolved.ClientCertificate, resolved.ClientKey = "", ""
+	}
+
+	if _, err := buildTLSConfig(&resolved); err != nil {
+		return err
+	}
+
+	if monitor.ProxyURL != "" && !HasSecretPlaceholder(monitor.ProxyURL) {
+		if _, err := parseProxyURL(monitor.ProxyURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildTLSConfig builds the TLS configuration for a monitor's client certificate and CA bundle
+func buildTLSConfig(monitor *database.Monitor) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: monitor.InsecureSkipVerify,
+	}
+
+	if monitor.CACertificates != "" {
+		// Trust the custom CAs in addition to the system roots
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(monitor.CACertificates)) {
+			return nil, errors.New("no valid certificates found in CA bundle")
+		}
+		config.RootCAs = pool
+	}
+
+	if monitor.ClientCertificate != "" || monitor.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(monitor.ClientCertificate), []byte(monitor.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// parseProxyURL parses and validates a monitor proxy URL
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %q", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, errors.New("proxy URL is missing a host")
+	}
+
+	return proxyURL, nil
+}
+
+// newHTTPClient creates an HTTP client using the monitor's TLS and proxy settings
+func newHTTPClient(monitor *database.Monitor) (*http.Client, error) {
+	tlsConfig, err := buildTLSConfig(monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// Each check makes a single request on a fresh transport, so pooled connections would
+	// only linger until the idle timeout
+	transport.DisableKeepAlives = true
+
+	if monitor.ProxyURL != "" {
+		proxyURL, err := parseProxyURL(monitor.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		// net/http handles http, https and socks5 proxies natively
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{
+		Timeout:   time.Duration(monitor.TimeoutSeconds) * time.Second,
+		Transport: transport,
+	}, nil
+}
+
+// dialMonitor opens a TCP connection to addr, going through the monitor's proxy if one is set
+func dialMonitor(monitor *database.Monitor, addr string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if monitor.ProxyURL == "" {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	proxyURL, err := parseProxyURL(monitor.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme == "socks5" {
+		socksDialer, err := proxy.FromURL(proxyURL, dialer)
+		if err != nil {
+			return nil, err
+		}
+		if contextDialer, ok := socksDialer.(proxy.ContextDialer); ok {
+			return contextDialer.DialContext(ctx, "tcp", addr)
+		}
+		return socksDialer.Dial("tcp", addr)
+	}
+
+	return dialHTTPConnect(ctx, dialer, proxyURL, addr)
+}
+
+// dialHTTPConnect opens a tunnel to addr through an HTTP(S) proxy using CONNECT
+func dialHTTPConnect(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT failed: %s", resp.Status)
+	}
+
+	return conn, nil
+}
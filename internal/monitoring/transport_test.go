@@ -0,0 +1,36 @@
+package monitoring
+
+import (
+	"testing"
+
+	"vigil/internal/database"
+)
+
+func TestValidateTransportConfig(t *testing.T) {
+	const placeholder = "{{secrets.TLS}}"
+
+	tests := []struct {
+		name    string
+		monitor database.Monitor
+		wantErr bool
+	}{
+		{name: "nothing configured"},
+		{name: "invalid CA bundle", monitor: database.Monitor{CACertificates: "not a certificate"}, wantErr: true},
+		{name: "CA bundle placeholder", monitor: database.Monitor{CACertificates: placeholder}},
+		{name: "invalid client certificate", monitor: database.Monitor{ClientCertificate: "not a certificate", ClientKey: "not a key"}, wantErr: true},
+		{name: "client key placeholder", monitor: database.Monitor{ClientCertificate: "not a certificate", ClientKey: placeholder}},
+		{name: "invalid proxy scheme", monitor: database.Monitor{ProxyURL: "ftp://proxy.internal"}, wantErr: true},
+		{name: "proxy placeholder", monitor: database.Monitor{ProxyURL: "http://user:" + placeholder + "@proxy.internal:3128"}},
+		{name: "placeholder does not skip other fields", monitor: database.Monitor{ClientKey: placeholder, ClientCertificate: placeholder, ProxyURL: "ftp://proxy.internal"}, wantErr: true},
+		{name: "proxy placeholder does not skip the CA bundle", monitor: database.Monitor{ProxyURL: placeholder, CACertificates: "not a certificate"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTransportConfig(&tt.monitor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTransportConfig error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
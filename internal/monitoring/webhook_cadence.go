@@ -0,0 +1,199 @@
+package monitoring
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// webhookCadenceSchedule is how often webhooks are checked for missed deliveries
+const webhookCadenceSchedule = "0 * * * * *"
+
+// maxBusinessDaysScanned bounds how far back business hours are counted; a webhook that
+// has been quiet for longer is silent regardless of its window
+const maxBusinessDaysScanned = 400
+
+// businessHours is a webhook's parsed business-hours window
+type businessHours struct {
+	location *time.Location
+	start    time.Duration // offset from midnight
+	end      time.Duration
+	days     map[time.Weekday]bool
+}
+
+// ValidateWebhookCadence checks a webhook's expected cadence settings
+func ValidateWebhookCadence(webhook *database.Webhook) error {
+	if webhook.ExpectedCadenceMinutes < 0 {
+		return fmt.Errorf("expected cadence must not be negative")
+	}
+	if !webhook.BusinessHoursOnly {
+		return nil
+	}
+	_, err := parseBusinessHours(webhook)
+	return err
+}
+
+// RecordWebhookReceived notes that a delivery arrived and resolves any silence alert
+func (s *Service) RecordWebhookReceived(webhook *database.Webhook, receivedAt time.Time) {
+	if err := s.db.Model(&database.Webhook{}).Where("id = ?", webhook.ID).
+		Update("last_received_at", receivedAt).Error; err != nil {
+		s.log.Errorf("Failed to record delivery time for webhook %d: %v", webhook.ID, err)
+	}
+
+	if webhook.ExpectedCadenceMinutes > 0 {
+		s.resolveWebhookAlerts(webhook.ID, "webhook_silent")
+	}
+}
+
+// checkSilentWebhooks raises an alert for every webhook that missed its expected cadence
+func (s *Service) checkSilentWebhooks() {
+	var webhooks []database.Webhook
+	if err := s.db.Where("is_active = ? AND expected_cadence_minutes > 0", true).Find(&webhooks).Error; err != nil {
+		s.log.Errorf("Failed to load webhooks with an expected cadence: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range webhooks {
+		webhook := &webhooks[i]
+
+		silent, err := webhookSilent(webhook, now)
+		if err != nil {
+			s.log.Errorf("Failed to check cadence for webhook %d: %v", webhook.ID, err)
+			continue
+		}
+
+		if silent {
+			s.createWebhookAlert(webhook, "webhook_silent",
+				fmt.Sprintf("Webhook %s has not received a delivery in the last %d minutes", webhook.Name, webhook.ExpectedCadenceMinutes),
+				"high")
+		}
+	}
+}
+
+// webhookSilent reports whether a webhook has gone longer than its cadence without a
+// delivery. With business hours only time inside the window counts, and no alert is
+// raised outside it.
+func webhookSilent(webhook *database.Webhook, now time.Time) (bool, error) {
+	since := webhook.CreatedAt
+	if webhook.LastReceivedAt != nil && webhook.LastReceivedAt.After(since) {
+		since = *webhook.LastReceivedAt
+	}
+
+	cadence := time.Duration(webhook.ExpectedCadenceMinutes) * time.Minute
+
+	if !webhook.BusinessHoursOnly {
+		return now.Sub(since) >= cadence, nil
+	}
+
+	hours, err := parseBusinessHours(webhook)
+	if err != nil {
+		return false, err
+	}
+
+	if !hours.contains(now) {
+		return false, nil
+	}
+
+	return hours.elapsed(since, now) >= cadence, nil
+}
+
+// parseBusinessHours parses a webhook's business-hours settings
+func parseBusinessHours(webhook *database.Webhook) (*businessHours, error) {
+	timezone := webhook.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	start, err := parseClock(webhook.BusinessHoursStart, 9*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(webhook.BusinessHoursEnd, 17*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("business hours must end after they start")
+	}
+
+	days := make(map[time.Weekday]bool)
+	spec := webhook.BusinessDays
+	if strings.TrimSpace(spec) == "" {
+		spec = "1,2,3,4,5"
+	}
+	for _, item := range strings.Split(spec, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || day < 0 || day > 6 {
+			return nil, fmt.Errorf("invalid business day %q, use 0 (Sunday) to 6 (Saturday)", item)
+		}
+		days[time.Weekday(day)] = true
+	}
+
+	return &businessHours{location: location, start: start, end: end, days: days}, nil
+}
+
+// parseClock parses an HH:MM time of day into an offset from midnight
+func parseClock(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// window returns the business-hours window on the day containing t
+func (h *businessHours) window(t time.Time) (time.Time, time.Time, bool) {
+	local := t.In(h.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, h.location)
+	if !h.days[midnight.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	return midnight.Add(h.start), midnight.Add(h.end), true
+}
+
+// contains reports whether t falls inside business hours
+func (h *businessHours) contains(t time.Time) bool {
+	start, end, ok := h.window(t)
+	return ok && !t.Before(start) && t.Before(end)
+}
+
+// elapsed returns how much business time passed between from and to
+func (h *businessHours) elapsed(from, to time.Time) time.Duration {
+	var total time.Duration
+
+	day := to
+	for i := 0; i < maxBusinessDaysScanned; i++ {
+		if start, end, ok := h.window(day); ok {
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+			}
+		}
+
+		local := day.In(h.location)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, h.location)
+		if !midnight.After(from) {
+			return total
+		}
+		day = midnight.Add(-time.Nanosecond)
+	}
+
+	// Quiet for longer than we are willing to scan
+	return time.Duration(1<<63 - 1)
+}
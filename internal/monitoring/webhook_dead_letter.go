@@ -0,0 +1,96 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// RefreshDeadLetterAlert raises or resolves a webhook's dead-letter alert based on the
+// current queue depth
+func (s *Service) RefreshDeadLetterAlert(webhook *database.Webhook) {
+	if webhook.DeadLetterAlertThreshold <= 0 {
+		s.resolveWebhookAlerts(webhook.ID, "webhook_dead_letter")
+		return
+	}
+
+	var depth int64
+	if err := s.db.Model(&database.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, "dead_letter").
+		Count(&depth).Error; err != nil {
+		s.log.Errorf("Failed to count dead letters for webhook %d: %v", webhook.ID, err)
+		return
+	}
+
+	if depth >= int64(webhook.DeadLetterAlertThreshold) {
+		s.createWebhookAlert(webhook, "webhook_dead_letter",
+			fmt.Sprintf("Webhook %s has %d deliveries in its dead-letter queue", webhook.Name, depth),
+			"high")
+	} else {
+		s.resolveWebhookAlerts(webhook.ID, "webhook_dead_letter")
+	}
+}
+
+// refreshDeadLetterAlertByID loads a webhook and refreshes its dead-letter alert
+func (s *Service) refreshDeadLetterAlertByID(webhookID uint) {
+	var webhook database.Webhook
+	if err := s.db.First(&webhook, webhookID).Error; err != nil {
+		s.log.Errorf("Failed to load webhook %d: %v", webhookID, err)
+		return
+	}
+	s.RefreshDeadLetterAlert(&webhook)
+}
+
+// PurgeDeadLetters deletes dead-lettered deliveries of a webhook along with their attempt
+// logs. When deliveryIDs is empty the whole queue is purged.
+func (s *Service) PurgeDeadLetters(webhook *database.Webhook, deliveryIDs []uint) (int64, error) {
+	query := s.db.Model(&database.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, "dead_letter")
+	if len(deliveryIDs) > 0 {
+		query = query.Where("id IN ?", deliveryIDs)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := s.db.Where("webhook_delivery_id IN ?", ids).Delete(&database.WebhookDeliveryAttempt{}).Error; err != nil {
+		return 0, err
+	}
+	if err := s.db.Where("webhook_delivery_id IN ?", ids).Delete(&database.WebhookDeliveryTarget{}).Error; err != nil {
+		return 0, err
+	}
+
+	result := s.db.Where("id IN ? AND status = ?", ids, "dead_letter").Delete(&database.WebhookDelivery{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	s.RefreshDeadLetterAlert(webhook)
+	return result.RowsAffected, nil
+}
+
+// RedriveDeadLetters queues dead-lettered deliveries of a webhook for another round of
+// forwarding, optionally to an alternate URL. When deliveryIDs is empty the whole queue
+// is redriven.
+func (s *Service) RedriveDeadLetters(webhook *database.Webhook, deliveryIDs []uint, targetURL string) (int64, error) {
+	query := s.db.Model(&database.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, "dead_letter")
+	if len(deliveryIDs) > 0 {
+		query = query.Where("id IN ?", deliveryIDs)
+	}
+
+	result := query.Updates(replayUpdates(targetURL, time.Now()))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	s.RefreshDeadLetterAlert(webhook)
+	return result.RowsAffected, nil
+}
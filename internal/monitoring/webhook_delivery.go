@@ -0,0 +1,318 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// webhookDeliverySchedule is how often the worker looks for deliveries that are due
+const webhookDeliverySchedule = "*/5 * * * * *"
+
+// webhookDeliveryBatchSize bounds how many due deliveries one worker run picks up
+const webhookDeliveryBatchSize = 100
+
+// webhookDeliveryConcurrency bounds how many deliveries are forwarded at once per run
+const webhookDeliveryConcurrency = 10
+
+// Retry backoff for failed forwards: base * 2^(retry-1), capped at max
+const (
+	webhookRetryBaseDelay = 10 * time.Second
+	webhookRetryMaxDelay  = 15 * time.Minute
+)
+
+// Limits on the response data kept for each attempt
+const (
+	maxAttemptBodyBytes        = 4096
+	maxAttemptHeaderValueBytes = 256
+)
+
+// defaultForwardHeaders are forwarded when a webhook does not list its own
+var defaultForwardHeaders = []string{"Content-Type"}
+
+// forwardResult describes the outcome of one forward attempt
+type forwardResult struct {
+	StatusCode int
+	Headers    http.Header
+	Body       string
+	Err        error
+}
+
+// DispatchWebhookDelivery forwards a newly received delivery in the background.
+// Deliveries that are not picked up here are retried by the scheduled worker.
+func (s *Service) DispatchWebhookDelivery(deliveryID uint) {
+	go s.processWebhookDelivery(deliveryID)
+}
+
+// processDueWebhookDeliveries forwards deliveries whose next attempt is due, including
+// deliveries whose lease expired because a previous attempt never finished
+func (s *Service) processDueWebhookDeliveries() {
+	var deliveryIDs []uint
+	if err := s.db.Model(&database.WebhookDelivery{}).
+		Where("status IN ? AND next_attempt_at <= ?", []string{"pending", "retrying", "delivering"}, time.Now()).
+		Order("next_attempt_at").
+		Limit(webhookDeliveryBatchSize).
+		Pluck("id", &deliveryIDs).Error; err != nil {
+		s.log.Errorf("Failed to load due webhook deliveries: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, webhookDeliveryConcurrency)
+	for _, deliveryID := range deliveryIDs {
+		sem <- struct{}{}
+		go func(id uint) {
+			defer func() { <-sem }()
+			s.processWebhookDelivery(id)
+		}(deliveryID)
+	}
+
+	// Wait for the batch so the scheduler skips ticks until it finishes; claimDue's
+	// lease is what keeps two workers from sending the same attempt
+	for i := 0; i < cap(sem); i++ {
+		sem <- struct{}{}
+	}
+}
+
+// processWebhookDelivery makes one forward attempt for a delivery and records the outcome
+func (s *Service) processWebhookDelivery(deliveryID uint) {
+	var delivery database.WebhookDelivery
+	if err := s.db.First(&delivery, deliveryID).Error; err != nil {
+		s.log.Errorf("Failed to load webhook delivery %d: %v", deliveryID, err)
+		return
+	}
+
+	var webhook database.Webhook
+	if err := s.db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		s.log.Errorf("Failed to load webhook %d: %v", delivery.WebhookID, err)
+		return
+	}
+
+	if !s.claimWebhookDelivery(&delivery, &webhook) {
+		return
+	}
+
+	// Reload now that the delivery is ours, in case another worker updated it first
+	if err := s.db.First(&delivery, deliveryID).Error; err != nil {
+		s.log.Errorf("Failed to reload webhook delivery %d: %v", deliveryID, err)
+		return
+	}
+
+	now := time.Now()
+	targetURL := deliveryTargetURL(&delivery, &webhook)
+	result := s.forwardWebhookDelivery(&delivery, &webhook, targetURL, delivery.Payload)
+	err := result.Err
+
+	s.recordWebhookAttempt(&delivery, nil, targetURL, now, result)
+
+	updates := map[string]interface{}{
+		"response_code":   result.StatusCode,
+		"last_attempt_at": now,
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = "success"
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
+		s.resolveWebhookAlerts(webhook.ID, "webhook_failed")
+
+	case delivery.RetryCount < webhook.RetryCount:
+		retry := delivery.RetryCount + 1
+		updates["status"] = "retrying"
+		updates["retry_count"] = retry
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = now.Add(webhookRetryDelay(retry))
+
+	default:
+		// Retries are exhausted, park the delivery in the dead-letter queue
+		updates["status"] = "dead_letter"
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = nil
+		updates["dead_lettered_at"] = now
+	}
+
+	if err := s.db.Model(&database.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		s.log.Errorf("Failed to update webhook delivery %d: %v", delivery.ID, err)
+		return
+	}
+
+	if updates["status"] == "dead_letter" {
+		s.createWebhookAlert(&webhook, "webhook_failed",
+			fmt.Sprintf("Webhook %s failed to deliver to %s after %d retries: %v", webhook.Name, targetURL, delivery.RetryCount, err),
+			"high")
+		s.RefreshDeadLetterAlert(&webhook)
+	}
+}
+
+// claimWebhookDelivery marks a due delivery as in flight so only one worker forwards it
+func (s *Service) claimWebhookDelivery(delivery *database.WebhookDelivery, webhook *database.Webhook) bool {
+	return s.claimDue(&database.WebhookDelivery{}, delivery.ID, webhook)
+}
+
+// claimDue marks a due delivery or delivery target as in flight so only one worker
+// forwards it. The claim is a lease: if the attempt never finishes, it becomes due again.
+func (s *Service) claimDue(model interface{}, id uint, webhook *database.Webhook) bool {
+	now := time.Now()
+	lease := now.Add(webhookTimeout(webhook) + time.Minute)
+
+	result := s.db.Model(model).
+		Where("id = ? AND status IN ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)",
+			id, []string{"pending", "retrying", "delivering"}, now).
+		Updates(map[string]interface{}{
+			"status":          "delivering",
+			"next_attempt_at": lease,
+		})
+	if result.Error != nil {
+		s.log.Errorf("Failed to claim %T %d: %v", model, id, result.Error)
+		return false
+	}
+
+	return result.RowsAffected == 1
+}
+
+// recordWebhookAttempt stores the outcome of a forward attempt on the delivery's attempt
+// log. targetID is set for attempts to an additional destination.
+func (s *Service) recordWebhookAttempt(delivery *database.WebhookDelivery, targetID *uint, url string, attemptedAt time.Time, result forwardResult) {
+	query := s.db.Model(&database.WebhookDeliveryAttempt{}).Where("webhook_delivery_id = ?", delivery.ID)
+	if targetID != nil {
+		query = query.Where("target_id = ?", *targetID)
+	} else {
+		query = query.Where("target_id IS NULL")
+	}
+
+	var attemptCount int64
+	query.Count(&attemptCount)
+
+	headers := make(map[string]string, len(result.Headers))
+	for name, values := range result.Headers {
+		headers[name] = truncate(strings.Join(values, ", "), maxAttemptHeaderValueBytes)
+	}
+	encodedHeaders, _ := json.Marshal(headers)
+
+	attempt := database.WebhookDeliveryAttempt{
+		WebhookDeliveryID: delivery.ID,
+		TargetID:          targetID,
+		AttemptNumber:     int(attemptCount) + 1,
+		URL:               url,
+		Replay:            delivery.ReplayCount > 0,
+		AttemptedAt:       attemptedAt,
+		DurationMs:        int(time.Since(attemptedAt).Milliseconds()),
+		StatusCode:        result.StatusCode,
+		ResponseHeaders:   string(encodedHeaders),
+		ResponseBody:      result.Body,
+	}
+	if result.Err != nil {
+		attempt.Error = result.Err.Error()
+	}
+
+	if err := s.db.Create(&attempt).Error; err != nil {
+		s.log.Errorf("Failed to record attempt for webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// forwardWebhookDelivery sends body and the delivery's selected headers to targetURL
+func (s *Service) forwardWebhookDelivery(delivery *database.WebhookDelivery, webhook *database.Webhook, targetURL, body string) forwardResult {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout(webhook))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewBufferString(body))
+	if err != nil {
+		return forwardResult{Err: err}
+	}
+
+	var received map[string]string
+	if delivery.RequestHeaders != "" {
+		json.Unmarshal([]byte(delivery.RequestHeaders), &received)
+	}
+
+	for _, name := range forwardHeaderNames(webhook) {
+		if value, ok := received[http.CanonicalHeaderKey(name)]; ok {
+			req.Header.Set(name, value)
+		}
+	}
+
+	req.Header.Set("User-Agent", "Vigil-Webhook-Forwarder/1.0")
+	req.Header.Set("X-Vigil-Webhook-ID", fmt.Sprint(webhook.ID))
+	req.Header.Set("X-Vigil-Delivery-ID", fmt.Sprint(delivery.ID))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return forwardResult{Err: err}
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxAttemptBodyBytes))
+
+	// Drain the rest of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	result := forwardResult{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Body:       strings.ToValidUTF8(string(responseBody), ""),
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Err = fmt.Errorf("target responded with status %d", resp.StatusCode)
+	}
+
+	return result
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+// deliveryTargetURL returns where a delivery is forwarded, honouring a replay override
+func deliveryTargetURL(delivery *database.WebhookDelivery, webhook *database.Webhook) string {
+	if delivery.TargetURL != "" {
+		return delivery.TargetURL
+	}
+	return webhook.URL
+}
+
+// forwardHeaderNames returns the request headers to forward for a webhook
+func forwardHeaderNames(webhook *database.Webhook) []string {
+	if strings.TrimSpace(webhook.ForwardHeaders) == "" {
+		return defaultForwardHeaders
+	}
+
+	var names []string
+	for _, name := range strings.Split(webhook.ForwardHeaders, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// webhookTimeout returns the forward timeout for a webhook
+func webhookTimeout(webhook *database.Webhook) time.Duration {
+	if webhook.TimeoutSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(webhook.TimeoutSeconds) * time.Second
+}
+
+// webhookRetryDelay returns the backoff before the given retry
+func webhookRetryDelay(retry int) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := 1; i < retry && delay < webhookRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMaxDelay {
+		delay = webhookRetryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,303 @@
+package monitoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+)
+
+// transformFuncs are the helpers available to destination transform templates
+var transformFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+// transformData is what a destination transform template is executed with
+type transformData struct {
+	Payload    interface{}       // decoded JSON payload, nil when the body is not JSON
+	Body       string            // raw payload
+	Headers    map[string]string // received headers
+	WebhookID  uint
+	DeliveryID uint
+}
+
+// ValidateWebhookDestination checks a destination's URL, filter and transform template
+func ValidateWebhookDestination(destination *database.WebhookDestination) error {
+	target, err := url.Parse(destination.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid destination URL %q", destination.URL)
+	}
+
+	if _, err := ParseFilter(destination.FilterExpression); err != nil {
+		return fmt.Errorf("invalid filter expression: %v", err)
+	}
+
+	if _, err := parseTransform(destination.TransformTemplate); err != nil {
+		return fmt.Errorf("invalid transform template: %v", err)
+	}
+
+	return nil
+}
+
+// RouteWebhookDelivery creates a target for each of the webhook's active destinations
+// and forwards the ones whose filter matches. Destinations that filter the delivery out
+// are recorded as filtered.
+func (s *Service) RouteWebhookDelivery(webhook *database.Webhook, delivery *database.WebhookDelivery) {
+	var destinations []database.WebhookDestination
+	if err := s.db.Where("webhook_id = ? AND is_active = ?", webhook.ID, true).Find(&destinations).Error; err != nil {
+		s.log.Errorf("Failed to load destinations for webhook %d: %v", webhook.ID, err)
+		return
+	}
+
+	if len(destinations) == 0 {
+		return
+	}
+
+	payload, headers := DecodeDelivery(delivery)
+	now := time.Now()
+
+	var dispatch []uint
+	for _, destination := range destinations {
+		target := database.WebhookDeliveryTarget{
+			WebhookDeliveryID: delivery.ID,
+			DestinationID:     destination.ID,
+			URL:               destination.URL,
+			Status:            "pending",
+			NextAttemptAt:     &now,
+		}
+
+		filter, err := ParseFilter(destination.FilterExpression)
+		if err != nil {
+			target.Status = "failed"
+			target.LastError = fmt.Sprintf("invalid filter expression: %v", err)
+			target.NextAttemptAt = nil
+		} else if !filter.Match(payload, headers) {
+			target.Status = "filtered"
+			target.NextAttemptAt = nil
+		}
+
+		if err := s.db.Create(&target).Error; err != nil {
+			s.log.Errorf("Failed to route webhook delivery %d to destination %d: %v", delivery.ID, destination.ID, err)
+			continue
+		}
+
+		if target.Status == "pending" {
+			dispatch = append(dispatch, target.ID)
+		}
+	}
+
+	for _, targetID := range dispatch {
+		go s.processWebhookDeliveryTarget(targetID)
+	}
+}
+
+// processDueWebhookDeliveryTargets forwards delivery targets whose next attempt is due
+func (s *Service) processDueWebhookDeliveryTargets() {
+	var targetIDs []uint
+	if err := s.db.Model(&database.WebhookDeliveryTarget{}).
+		Where("status IN ? AND next_attempt_at <= ?", []string{"pending", "retrying", "delivering"}, time.Now()).
+		Order("next_attempt_at").
+		Limit(webhookDeliveryBatchSize).
+		Pluck("id", &targetIDs).Error; err != nil {
+		s.log.Errorf("Failed to load due webhook delivery targets: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, webhookDeliveryConcurrency)
+	for _, targetID := range targetIDs {
+		sem <- struct{}{}
+		go func(id uint) {
+			defer func() { <-sem }()
+			s.processWebhookDeliveryTarget(id)
+		}(targetID)
+	}
+
+	// Wait for the batch so the scheduler skips ticks until it finishes; claimDue's
+	// lease is what keeps two workers from sending the same attempt
+	for i := 0; i < cap(sem); i++ {
+		sem <- struct{}{}
+	}
+}
+
+// processWebhookDeliveryTarget makes one forward attempt to a destination and records the
+// outcome. Each target retries independently of the delivery and its other targets.
+func (s *Service) processWebhookDeliveryTarget(targetID uint) {
+	var target database.WebhookDeliveryTarget
+	if err := s.db.First(&target, targetID).Error; err != nil {
+		s.log.Errorf("Failed to load webhook delivery target %d: %v", targetID, err)
+		return
+	}
+
+	var delivery database.WebhookDelivery
+	if err := s.db.First(&delivery, target.WebhookDeliveryID).Error; err != nil {
+		s.log.Errorf("Failed to load webhook delivery %d: %v", target.WebhookDeliveryID, err)
+		return
+	}
+
+	var webhook database.Webhook
+	if err := s.db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		s.log.Errorf("Failed to load webhook %d: %v", delivery.WebhookID, err)
+		return
+	}
+
+	if !s.claimDue(&database.WebhookDeliveryTarget{}, target.ID, &webhook) {
+		return
+	}
+
+	// Reload now that the target is ours, in case another worker updated it first
+	if err := s.db.First(&target, targetID).Error; err != nil {
+		s.log.Errorf("Failed to reload webhook delivery target %d: %v", targetID, err)
+		return
+	}
+
+	now := time.Now()
+
+	var destination database.WebhookDestination
+	if err := s.db.First(&destination, target.DestinationID).Error; err != nil {
+		s.finishWebhookDeliveryTarget(&target, map[string]interface{}{
+			"status":          "failed",
+			"last_error":      "destination no longer exists",
+			"last_attempt_at": now,
+			"next_attempt_at": nil,
+		})
+		return
+	}
+
+	body, err := renderTransform(&destination, &delivery)
+	if err != nil {
+		// A template that fails on this payload will fail on every retry
+		s.finishWebhookDeliveryTarget(&target, map[string]interface{}{
+			"status":          "failed",
+			"last_error":      fmt.Sprintf("transform failed: %v", err),
+			"last_attempt_at": now,
+			"next_attempt_at": nil,
+		})
+		return
+	}
+
+	result := s.forwardWebhookDelivery(&delivery, &webhook, target.URL, body)
+	err = result.Err
+
+	s.recordWebhookAttempt(&delivery, &target.ID, target.URL, now, result)
+
+	updates := map[string]interface{}{
+		"response_code":   result.StatusCode,
+		"last_attempt_at": now,
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = "success"
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
+		s.resolveDestinationAlerts(destination.ID)
+
+	case target.RetryCount < webhook.RetryCount:
+		retry := target.RetryCount + 1
+		updates["status"] = "retrying"
+		updates["retry_count"] = retry
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = now.Add(webhookRetryDelay(retry))
+
+	default:
+		updates["status"] = "failed"
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = nil
+	}
+
+	s.finishWebhookDeliveryTarget(&target, updates)
+
+	if updates["status"] == "failed" {
+		webhookID, destinationID := webhook.ID, destination.ID
+		s.raiseAlert(&database.Alert{
+			WebhookID:            &webhookID,
+			WebhookDestinationID: &destinationID,
+			Type:                 "webhook_destination_failed",
+			Message:              fmt.Sprintf("Webhook %s failed to deliver to destination %s after %d retries: %v", webhook.Name, destination.Name, target.RetryCount, err),
+			Severity:             "high",
+		}, webhook.OrganizationID)
+	}
+}
+
+// resolveDestinationAlerts resolves the open delivery failure alerts of one destination,
+// leaving the webhook's own and its other destinations' alerts alone
+func (s *Service) resolveDestinationAlerts(destinationID uint) {
+	s.resolveAlertsWhere("webhook_destination_id = ? AND type = ? AND resolved_at IS NULL", destinationID, "webhook_destination_failed")
+}
+
+// finishWebhookDeliveryTarget stores the outcome of a target's attempt
+func (s *Service) finishWebhookDeliveryTarget(target *database.WebhookDeliveryTarget, updates map[string]interface{}) {
+	if err := s.db.Model(&database.WebhookDeliveryTarget{}).Where("id = ?", target.ID).Updates(updates).Error; err != nil {
+		s.log.Errorf("Failed to update webhook delivery target %d: %v", target.ID, err)
+	}
+}
+
+// replayWebhookDeliveryTargets queues the failed targets of the selected deliveries again
+func (s *Service) replayWebhookDeliveryTargets(deliveryIDs *gorm.DB) (int64, error) {
+	result := s.db.Model(&database.WebhookDeliveryTarget{}).
+		Where("status = ? AND webhook_delivery_id IN (?)", "failed", deliveryIDs).
+		Updates(map[string]interface{}{
+			"status":          "pending",
+			"retry_count":     0,
+			"last_error":      "",
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// renderTransform builds the body forwarded to a destination
+func renderTransform(destination *database.WebhookDestination, delivery *database.WebhookDelivery) (string, error) {
+	if destination.TransformTemplate == "" {
+		return delivery.Payload, nil
+	}
+
+	tmpl, err := parseTransform(destination.TransformTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	payload, headers := DecodeDelivery(delivery)
+	data := transformData{
+		Payload:    payload,
+		Body:       delivery.Payload,
+		Headers:    headers,
+		WebhookID:  delivery.WebhookID,
+		DeliveryID: delivery.ID,
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// parseTransform parses a destination transform template
+func parseTransform(source string) (*template.Template, error) {
+	return template.New("transform").Funcs(transformFuncs).Option("missingkey=zero").Parse(source)
+}
+
+// DecodeDelivery decodes a delivery's JSON payload and received headers. The payload is
+// nil when the body is not JSON.
+func DecodeDelivery(delivery *database.WebhookDelivery) (interface{}, map[string]string) {
+	var payload interface{}
+	if err := json.Unmarshal([]byte(delivery.Payload), &payload); err != nil {
+		payload = nil
+	}
+
+	headers := make(map[string]string)
+	if delivery.RequestHeaders != "" {
+		json.Unmarshal([]byte(delivery.RequestHeaders), &headers)
+	}
+
+	return payload, headers
+}
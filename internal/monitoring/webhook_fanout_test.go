@@ -0,0 +1,54 @@
+package monitoring
+
+import (
+	"testing"
+
+	"vigil/internal/database"
+)
+
+// TestDeliveryQueryFilter covers the q filter of the delivery list, which runs against
+// stored deliveries rather than live requests
+func TestDeliveryQueryFilter(t *testing.T) {
+	invoice := &database.WebhookDelivery{
+		Payload:        `{"type":"invoice.paid","data":{"amount":1200,"currency":"eur"}}`,
+		RequestHeaders: `{"Content-Type":"application/json","X-Github-Event":"push"}`,
+	}
+	form := &database.WebhookDelivery{
+		Payload:        `token=abc&type=invoice.paid`,
+		RequestHeaders: `{"Content-Type":"application/x-www-form-urlencoded"}`,
+	}
+	bare := &database.WebhookDelivery{Payload: `[1,2,3]`}
+
+	tests := []struct {
+		q        string
+		delivery *database.WebhookDelivery
+		want     bool
+	}{
+		{q: `type == "invoice.paid"`, delivery: invoice, want: true},
+		{q: `payload.data.amount > 1000 && payload.data.currency == "eur"`, delivery: invoice, want: true},
+		{q: `headers.X-GitHub-Event == "push"`, delivery: invoice, want: true},
+		{q: `headers.content-type contains "json"`, delivery: invoice, want: true},
+
+		// Non-JSON bodies have no payload fields but their headers still match
+		{q: `type == "invoice.paid"`, delivery: form, want: false},
+		{q: `payload.type != "invoice.paid"`, delivery: form, want: true},
+		{q: `headers.Content-Type contains "form"`, delivery: form, want: true},
+
+		// Array payloads are indexed and deliveries without stored headers have none
+		{q: `payload.2 == 3`, delivery: bare, want: true},
+		{q: `headers.Content-Type`, delivery: bare, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			filter, err := ParseFilter(tt.q)
+			if err != nil {
+				t.Fatalf("ParseFilter: %v", err)
+			}
+			payload, headers := DecodeDelivery(tt.delivery)
+			if got := filter.Match(payload, headers); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
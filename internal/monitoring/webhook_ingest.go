@@ -0,0 +1,130 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// maxIngestTokenGrace bounds how long a rotated ingest token keeps working
+const maxIngestTokenGrace = 7 * 24 * time.Hour
+
+// ParseSourceCIDRs parses a comma-separated list of IPs and CIDRs
+func ParseSourceCIDRs(spec string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid source address %q", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source CIDR %q", item)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// WebhookSourceAllowed reports whether a request from ip may reach the webhook's receiver
+func WebhookSourceAllowed(webhook *database.Webhook, ip string) bool {
+	if strings.TrimSpace(webhook.AllowedSourceCIDRs) == "" {
+		return true
+	}
+
+	networks, err := ParseSourceCIDRs(webhook.AllowedSourceCIDRs)
+	if err != nil {
+		return false
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowWebhookRequest counts a request against the webhook's per-minute rate limit. It
+// returns false with the time until the window resets when the limit is exceeded. If
+// Redis is unavailable requests are allowed.
+func (s *Service) AllowWebhookRequest(webhook *database.Webhook) (bool, time.Duration) {
+	if webhook.RateLimitPerMinute <= 0 {
+		return true, 0
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	window := now.Truncate(time.Minute)
+	key := fmt.Sprintf("webhook:%d:rate:%d", webhook.ID, window.Unix())
+
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		s.log.Errorf("Failed to rate limit webhook %d: %v", webhook.ID, err)
+		return true, 0
+	}
+
+	if count == 1 {
+		s.redis.Expire(ctx, key, 2*time.Minute)
+	}
+
+	if count > int64(webhook.RateLimitPerMinute) {
+		return false, window.Add(time.Minute).Sub(now)
+	}
+	return true, 0
+}
+
+// RotateIngestToken issues a new ingest token for a webhook. The current token keeps
+// working for the grace period so providers can be reconfigured without dropping
+// deliveries.
+func (s *Service) RotateIngestToken(webhook *database.Webhook, grace time.Duration) error {
+	if grace < 0 || grace > maxIngestTokenGrace {
+		return fmt.Errorf("grace period must be between 0 and %v", maxIngestTokenGrace)
+	}
+
+	token, err := database.NewIngestToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(grace)
+	updates := map[string]interface{}{
+		"ingest_token":                     token,
+		"previous_ingest_token":            webhook.IngestToken,
+		"previous_ingest_token_expires_at": expiresAt,
+	}
+	if grace == 0 {
+		updates["previous_ingest_token"] = ""
+		updates["previous_ingest_token_expires_at"] = nil
+	}
+
+	if err := s.db.Model(&database.Webhook{}).Where("id = ?", webhook.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return s.db.First(webhook, webhook.ID).Error
+}
@@ -0,0 +1,77 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// maxMockDelay keeps simulated latency below the server's write timeout
+const maxMockDelay = 25 * time.Second
+
+// MockResponse is the response a webhook in mock mode returns to its caller
+type MockResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+	Delay      time.Duration
+	Failed     bool // a simulated failure was returned
+}
+
+// ValidateWebhookMock checks a webhook's mock response settings
+func ValidateWebhookMock(webhook *database.Webhook) error {
+	if webhook.MockStatusCode != 0 && (webhook.MockStatusCode < 100 || webhook.MockStatusCode > 599) {
+		return fmt.Errorf("mock status code must be between 100 and 599")
+	}
+	if webhook.MockFailureStatusCode != 0 && (webhook.MockFailureStatusCode < 100 || webhook.MockFailureStatusCode > 599) {
+		return fmt.Errorf("mock failure status code must be between 100 and 599")
+	}
+	if webhook.MockDelayMs < 0 || time.Duration(webhook.MockDelayMs)*time.Millisecond > maxMockDelay {
+		return fmt.Errorf("mock delay must be between 0 and %d ms", maxMockDelay.Milliseconds())
+	}
+	if webhook.MockFailureRate < 0 || webhook.MockFailureRate > 100 {
+		return fmt.Errorf("mock failure rate must be between 0 and 100")
+	}
+	if webhook.MockHeaders != "" {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(webhook.MockHeaders), &headers); err != nil {
+			return fmt.Errorf("mock headers must be a JSON object of strings")
+		}
+	}
+	return nil
+}
+
+// MockWebhookResponse builds the configured mock response for a webhook, rolling for a
+// simulated failure
+func MockWebhookResponse(webhook *database.Webhook) MockResponse {
+	response := MockResponse{
+		StatusCode: webhook.MockStatusCode,
+		Body:       webhook.MockBody,
+		Delay:      time.Duration(webhook.MockDelayMs) * time.Millisecond,
+	}
+
+	if response.StatusCode == 0 {
+		response.StatusCode = 200
+	}
+	if response.Delay > maxMockDelay {
+		response.Delay = maxMockDelay
+	}
+	if webhook.MockHeaders != "" {
+		json.Unmarshal([]byte(webhook.MockHeaders), &response.Headers)
+	}
+
+	if webhook.MockFailureRate > 0 && rand.Intn(100) < webhook.MockFailureRate {
+		response.Failed = true
+		response.StatusCode = webhook.MockFailureStatusCode
+		if response.StatusCode == 0 {
+			response.StatusCode = 500
+		}
+		response.Headers = map[string]string{"Content-Type": "application/json"}
+		response.Body = `{"error":"simulated failure"}`
+	}
+
+	return response
+}
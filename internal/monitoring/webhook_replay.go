@@ -0,0 +1,127 @@
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"vigil/internal/database"
+)
+
+// ErrDeliveryInFlight is returned when replaying a delivery that is still being forwarded
+var ErrDeliveryInFlight = errors.New("delivery is still being forwarded")
+
+// ErrDeliveryNotReplayable is returned when replaying a delivery that was never accepted
+// for forwarding, such as one rejected by signature or schema validation
+var ErrDeliveryNotReplayable = errors.New("delivery cannot be replayed")
+
+// inFlightStatuses are the statuses of deliveries the worker has yet to finish
+var inFlightStatuses = []string{"pending", "retrying", "delivering"}
+
+// replayableStatuses are the delivery statuses that can be replayed
+var replayableStatuses = []string{"success", "failed", "dead_letter", "captured"}
+
+// failedStatuses are the statuses of deliveries that never reached their target
+var failedStatuses = []string{"failed", "dead_letter"}
+
+// ValidateReplayURL checks an alternate replay target
+func ValidateReplayURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid replay URL %q", rawURL)
+	}
+
+	return nil
+}
+
+// replayUpdates returns the column changes that queue a delivery for replay
+func replayUpdates(targetURL string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"status":           "pending",
+		"retry_count":      0,
+		"last_error":       "",
+		"next_attempt_at":  now,
+		"target_url":       targetURL,
+		"replay_count":     gorm.Expr("replay_count + 1"),
+		"dead_lettered_at": nil,
+	}
+}
+
+// ReplayWebhookDelivery queues a finished delivery to be forwarded again, optionally to an
+// alternate URL. Its attempts are logged on the original delivery and flagged as replays.
+// Without an alternate URL, destinations that failed are retried as well.
+func (s *Service) ReplayWebhookDelivery(delivery *database.WebhookDelivery, targetURL string) error {
+	result := s.db.Model(&database.WebhookDelivery{}).
+		Where("id = ? AND status IN ?", delivery.ID, replayableStatuses).
+		Updates(replayUpdates(targetURL, time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return s.replayRefusal(delivery.ID)
+	}
+
+	s.DispatchWebhookDelivery(delivery.ID)
+
+	if targetURL == "" {
+		if _, err := s.replayWebhookDeliveryTargets(s.db.Model(&database.WebhookDelivery{}).Select("id").Where("id = ?", delivery.ID)); err != nil {
+			s.log.Errorf("Failed to replay destinations of webhook delivery %d: %v", delivery.ID, err)
+		}
+	}
+
+	if delivery.Status == "dead_letter" {
+		s.refreshDeadLetterAlertByID(delivery.WebhookID)
+	}
+
+	return nil
+}
+
+// replayRefusal explains why a delivery was not queued for replay, re-reading its status
+// since it may have changed after the handler loaded it
+func (s *Service) replayRefusal(deliveryID uint) error {
+	var status string
+	if err := s.db.Model(&database.WebhookDelivery{}).Where("id = ?", deliveryID).
+		Pluck("status", &status).Error; err != nil {
+		return err
+	}
+
+	for _, inFlight := range inFlightStatuses {
+		if status == inFlight {
+			return ErrDeliveryInFlight
+		}
+	}
+	return fmt.Errorf("%w: status is %q", ErrDeliveryNotReplayable, status)
+}
+
+// ReplayFailedWebhookDeliveries queues every failed delivery of a webhook received in
+// [from, to) for replay and returns how many were queued. Without an alternate URL, failed
+// destinations of deliveries in the range are queued too. The scheduled worker sends them.
+func (s *Service) ReplayFailedWebhookDeliveries(webhookID uint, from, to time.Time, targetURL string) (int64, error) {
+	result := s.db.Model(&database.WebhookDelivery{}).
+		Where("webhook_id = ? AND status IN ? AND delivered_at >= ? AND delivered_at < ?", webhookID, failedStatuses, from, to).
+		Updates(replayUpdates(targetURL, time.Now()))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	queued := result.RowsAffected
+
+	if targetURL == "" {
+		targets, err := s.replayWebhookDeliveryTargets(s.db.Model(&database.WebhookDelivery{}).Select("id").
+			Where("webhook_id = ? AND delivered_at >= ? AND delivered_at < ?", webhookID, from, to))
+		if err != nil {
+			return queued, err
+		}
+		queued += targets
+	}
+
+	s.refreshDeadLetterAlertByID(webhookID)
+	return queued, nil
+}
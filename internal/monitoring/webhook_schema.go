@@ -0,0 +1,170 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"vigil/internal/database"
+)
+
+// webhookSchemaURL is the resource name webhook schemas are compiled under
+const webhookSchemaURL = "vigil://webhook-schema.json"
+
+// invalidPayloadWindow is the window over which the invalid payload rate is measured
+const invalidPayloadWindow = 10 * time.Minute
+
+// invalidPayloadMinSample is how many deliveries a window needs before its rate is judged
+const invalidPayloadMinSample = 10
+
+// maxValidationErrors bounds how many schema violations are stored per delivery
+const maxValidationErrors = 20
+
+// compiledSchema caches a webhook's compiled schema alongside the source it came from
+type compiledSchema struct {
+	source string
+	schema *jsonschema.Schema
+}
+
+var (
+	schemaCache   = make(map[uint]compiledSchema)
+	schemaCacheMu sync.Mutex
+)
+
+// CompileWebhookSchema parses and compiles a JSON Schema document. External references
+// are not resolved.
+func CompileWebhookSchema(source string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("external schema reference %q is not allowed", url)
+	}
+
+	if err := compiler.AddResource(webhookSchemaURL, strings.NewReader(source)); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	schema, err := compiler.Compile(webhookSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	return schema, nil
+}
+
+// ValidateWebhookPayload checks a payload against the webhook's JSON Schema and returns
+// the violations found. A webhook without a schema accepts every payload.
+func ValidateWebhookPayload(webhook *database.Webhook, body []byte) ([]string, error) {
+	if strings.TrimSpace(webhook.JSONSchema) == "" {
+		return nil, nil
+	}
+
+	schema, err := webhookSchema(webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return []string{"payload is not valid JSON"}, nil
+	}
+
+	err = schema.Validate(payload)
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var violations []string
+	for _, unit := range validationErr.BasicOutput().Errors {
+		// The root unit only says that validation failed
+		if unit.KeywordLocation == "" {
+			continue
+		}
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		violations = append(violations, fmt.Sprintf("%s: %s", location, unit.Error))
+		if len(violations) == maxValidationErrors {
+			break
+		}
+	}
+
+	if len(violations) == 0 {
+		violations = []string{validationErr.Error()}
+	}
+
+	return violations, nil
+}
+
+// webhookSchema returns the compiled schema for a webhook, recompiling it when it changed
+func webhookSchema(webhook *database.Webhook) (*jsonschema.Schema, error) {
+	schemaCacheMu.Lock()
+	defer schemaCacheMu.Unlock()
+
+	if cached, ok := schemaCache[webhook.ID]; ok && cached.source == webhook.JSONSchema {
+		return cached.schema, nil
+	}
+
+	schema, err := CompileWebhookSchema(webhook.JSONSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCache[webhook.ID] = compiledSchema{source: webhook.JSONSchema, schema: schema}
+	return schema, nil
+}
+
+// RecordWebhookPayloadValidation counts a validated payload and raises or resolves an
+// alert depending on the invalid rate in the current window
+func (s *Service) RecordWebhookPayloadValidation(webhook *database.Webhook, valid bool) {
+	if webhook.InvalidPayloadAlertPercent <= 0 {
+		return
+	}
+
+	ctx := context.Background()
+	window := time.Now().Unix() / int64(invalidPayloadWindow.Seconds())
+	key := fmt.Sprintf("webhook:%d:payload_validation:%d", webhook.ID, window)
+
+	var invalidIncrement int64
+	if !valid {
+		invalidIncrement = 1
+	}
+
+	pipe := s.redis.TxPipeline()
+	total := pipe.HIncrBy(ctx, key, "total", 1)
+	invalid := pipe.HIncrBy(ctx, key, "invalid", invalidIncrement)
+	pipe.Expire(ctx, key, 2*invalidPayloadWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.log.Errorf("Failed to count payload validation for webhook %d: %v", webhook.ID, err)
+		return
+	}
+
+	if total.Val() < invalidPayloadMinSample {
+		return
+	}
+
+	rate := float64(invalid.Val()) / float64(total.Val()) * 100
+	if rate >= float64(webhook.InvalidPayloadAlertPercent) {
+		s.createWebhookAlert(webhook, "webhook_invalid_payload",
+			fmt.Sprintf("Webhook %s: %.0f%% of %d deliveries in the last %v failed schema validation", webhook.Name, rate, total.Val(), invalidPayloadWindow),
+			"medium")
+	} else {
+		s.resolveWebhookAlerts(webhook.ID, "webhook_invalid_payload")
+	}
+}
@@ -0,0 +1,218 @@
+package monitoring
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// Webhook signature verification schemes
+const (
+	SignatureSchemeNone       = "none"
+	SignatureSchemeHMACSHA256 = "hmac_sha256"
+	SignatureSchemeGitHub     = "github"
+	SignatureSchemeStripe     = "stripe"
+	SignatureSchemeSlack      = "slack"
+)
+
+// defaultSignatureHeader is checked by the generic hmac_sha256 scheme when no header is configured
+const defaultSignatureHeader = "X-Signature"
+
+// defaultSignatureTolerance bounds the timestamp skew accepted by Stripe and Slack signatures
+const defaultSignatureTolerance = 5 * time.Minute
+
+// ErrSignatureMismatch is returned when a signature does not match the payload
+var ErrSignatureMismatch = errors.New("signature does not match payload")
+
+// ValidSignatureScheme reports whether scheme is a supported verification scheme
+func ValidSignatureScheme(scheme string) bool {
+	switch scheme {
+	case "", SignatureSchemeNone, SignatureSchemeHMACSHA256, SignatureSchemeGitHub, SignatureSchemeStripe, SignatureSchemeSlack:
+		return true
+	}
+	return false
+}
+
+// VerifyWebhookSignature checks an incoming delivery against the webhook's verification
+// scheme. header looks up a request header by name. It returns nil when the scheme is
+// none or the signature is valid.
+func VerifyWebhookSignature(webhook *database.Webhook, header func(string) string, body []byte, now time.Time) error {
+	if webhook.VerificationScheme == "" || webhook.VerificationScheme == SignatureSchemeNone {
+		return nil
+	}
+
+	if webhook.Secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+
+	tolerance := defaultSignatureTolerance
+	if webhook.SignatureToleranceSeconds > 0 {
+		tolerance = time.Duration(webhook.SignatureToleranceSeconds) * time.Second
+	}
+
+	secret := []byte(webhook.Secret)
+
+	switch webhook.VerificationScheme {
+	case SignatureSchemeHMACSHA256:
+		name := webhook.SignatureHeader
+		if name == "" {
+			name = defaultSignatureHeader
+		}
+		return verifyGenericHMAC(secret, header(name), body)
+
+	case SignatureSchemeGitHub:
+		return verifyGitHub(secret, header("X-Hub-Signature-256"), body)
+
+	case SignatureSchemeStripe:
+		return verifyStripe(secret, header("Stripe-Signature"), body, now, tolerance)
+
+	case SignatureSchemeSlack:
+		return verifySlack(secret, header("X-Slack-Signature"), header("X-Slack-Request-Timestamp"), body, now, tolerance)
+
+	default:
+		return fmt.Errorf("unsupported verification scheme %q", webhook.VerificationScheme)
+	}
+}
+
+// computeHMAC returns the HMAC-SHA256 of the given parts
+func computeHMAC(secret []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+// verifyGenericHMAC accepts a hex or base64 HMAC-SHA256 of the body, optionally prefixed with "sha256="
+func verifyGenericHMAC(secret []byte, signature string, body []byte) error {
+	if signature == "" {
+		return errors.New("missing signature header")
+	}
+
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	expected := computeHMAC(secret, body)
+
+	if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return nil
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return nil
+	}
+
+	return ErrSignatureMismatch
+}
+
+// verifyGitHub checks an X-Hub-Signature-256 header
+func verifyGitHub(secret []byte, signature string, body []byte) error {
+	if signature == "" {
+		return errors.New("missing X-Hub-Signature-256 header")
+	}
+
+	encoded, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return errors.New("malformed X-Hub-Signature-256 header")
+	}
+
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return errors.New("malformed X-Hub-Signature-256 header")
+	}
+
+	if !hmac.Equal(decoded, computeHMAC(secret, body)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// verifyStripe checks a Stripe-Signature header of the form t=<unix>,v1=<hex>[,v1=<hex>...]
+func verifyStripe(secret []byte, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	if signature == "" {
+		return errors.New("missing Stripe-Signature header")
+	}
+
+	var timestamp string
+	var candidates [][]byte
+	for _, item := range strings.Split(signature, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if decoded, err := hex.DecodeString(value); err == nil {
+				candidates = append(candidates, decoded)
+			}
+		}
+	}
+
+	if timestamp == "" || len(candidates) == 0 {
+		return errors.New("malformed Stripe-Signature header")
+	}
+
+	if err := checkTimestamp(timestamp, now, tolerance); err != nil {
+		return err
+	}
+
+	expected := computeHMAC(secret, []byte(timestamp), []byte("."), body)
+	for _, candidate := range candidates {
+		if hmac.Equal(candidate, expected) {
+			return nil
+		}
+	}
+
+	return ErrSignatureMismatch
+}
+
+// verifySlack checks Slack's v0 request signature
+func verifySlack(secret []byte, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) error {
+	if signature == "" || timestamp == "" {
+		return errors.New("missing X-Slack-Signature or X-Slack-Request-Timestamp header")
+	}
+
+	if err := checkTimestamp(timestamp, now, tolerance); err != nil {
+		return err
+	}
+
+	encoded, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return errors.New("malformed X-Slack-Signature header")
+	}
+
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return errors.New("malformed X-Slack-Signature header")
+	}
+
+	if !hmac.Equal(decoded, computeHMAC(secret, []byte("v0:"+timestamp+":"), body)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// checkTimestamp rejects signatures whose unix timestamp is outside the tolerance window
+func checkTimestamp(timestamp string, now time.Time, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > tolerance {
+		return fmt.Errorf("signature timestamp is outside the %v tolerance", tolerance)
+	}
+
+	return nil
+}
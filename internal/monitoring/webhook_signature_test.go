@@ -0,0 +1,175 @@
+package monitoring
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"vigil/internal/database"
+)
+
+// Published test vectors
+const (
+	// RFC 4231, test case 2
+	rfc4231Key       = "Jefe"
+	rfc4231Data      = "what do ya want for nothing?"
+	rfc4231Signature = "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+
+	// GitHub's "Validating webhook deliveries" example
+	githubSecret    = "It's a Secret to Everybody"
+	githubBody      = "Hello, World!"
+	githubSignature = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	// Slack's "Verifying requests from Slack" example
+	slackSecret    = "8f742231b10e8888abcd99yyyzzz85a5"
+	slackTimestamp = "1531420618"
+	slackBody      = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	slackSignature = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+)
+
+// stripeSignature signs a payload the way Stripe documents it, independently of the verifier
+func stripeSignature(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	slackTime := time.Unix(1531420618, 0)
+	stripeTime := time.Unix(1700000000, 0)
+	stripeTimestamp := strconv.FormatInt(stripeTime.Unix(), 10)
+	stripeBody := `{"id":"evt_1","type":"charge.succeeded"}`
+	stripeValid := stripeSignature("whsec_test", stripeTimestamp, stripeBody)
+
+	rfcRaw, _ := hex.DecodeString(rfc4231Signature)
+
+	tests := []struct {
+		name     string
+		scheme   string
+		secret   string
+		header   string // for hmac_sha256; empty uses the default header
+		headers  map[string]string
+		body     string
+		now      time.Time
+		wantErr  bool
+		mismatch bool // the error must be ErrSignatureMismatch
+	}{
+		// Generic HMAC
+		{name: "hmac hex", scheme: SignatureSchemeHMACSHA256, secret: rfc4231Key, body: rfc4231Data,
+			headers: map[string]string{"X-Signature": rfc4231Signature}},
+		{name: "hmac sha256= prefix", scheme: SignatureSchemeHMACSHA256, secret: rfc4231Key, body: rfc4231Data,
+			headers: map[string]string{"X-Signature": "sha256=" + rfc4231Signature}},
+		{name: "hmac base64 in custom header", scheme: SignatureSchemeHMACSHA256, secret: rfc4231Key, body: rfc4231Data, header: "X-Hook-Sig",
+			headers: map[string]string{"X-Hook-Sig": base64.StdEncoding.EncodeToString(rfcRaw)}},
+		{name: "hmac wrong secret", scheme: SignatureSchemeHMACSHA256, secret: "Jeff", body: rfc4231Data,
+			headers: map[string]string{"X-Signature": rfc4231Signature}, wantErr: true, mismatch: true},
+		{name: "hmac missing header", scheme: SignatureSchemeHMACSHA256, secret: rfc4231Key, body: rfc4231Data, wantErr: true},
+		{name: "hmac garbage", scheme: SignatureSchemeHMACSHA256, secret: rfc4231Key, body: rfc4231Data,
+			headers: map[string]string{"X-Signature": "not a signature"}, wantErr: true, mismatch: true},
+
+		// GitHub
+		{name: "github valid", scheme: SignatureSchemeGitHub, secret: githubSecret, body: githubBody,
+			headers: map[string]string{"X-Hub-Signature-256": githubSignature}},
+		{name: "github wrong secret", scheme: SignatureSchemeGitHub, secret: "It's a Secret to Nobody", body: githubBody,
+			headers: map[string]string{"X-Hub-Signature-256": githubSignature}, wantErr: true, mismatch: true},
+		{name: "github modified body", scheme: SignatureSchemeGitHub, secret: githubSecret, body: githubBody + " ",
+			headers: map[string]string{"X-Hub-Signature-256": githubSignature}, wantErr: true, mismatch: true},
+		{name: "github sha1 header format", scheme: SignatureSchemeGitHub, secret: githubSecret, body: githubBody,
+			headers: map[string]string{"X-Hub-Signature-256": "sha1=757107ea0eb2509fc211221cce984b8a37570b6d"}, wantErr: true},
+		{name: "github non-hex", scheme: SignatureSchemeGitHub, secret: githubSecret, body: githubBody,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=zz"}, wantErr: true},
+		{name: "github missing header", scheme: SignatureSchemeGitHub, secret: githubSecret, body: githubBody, wantErr: true},
+
+		// Stripe
+		{name: "stripe valid", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime,
+			headers: map[string]string{"Stripe-Signature": "t=" + stripeTimestamp + ",v1=" + stripeValid}},
+		{name: "stripe valid among rolled secrets", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime.Add(time.Minute),
+			headers: map[string]string{"Stripe-Signature": "t=" + stripeTimestamp + ",v1=" + stripeSignature("whsec_old", stripeTimestamp, stripeBody) + ",v1=" + stripeValid + ",v0=ignored"}},
+		{name: "stripe wrong secret", scheme: SignatureSchemeStripe, secret: "whsec_other", body: stripeBody, now: stripeTime,
+			headers: map[string]string{"Stripe-Signature": "t=" + stripeTimestamp + ",v1=" + stripeValid}, wantErr: true, mismatch: true},
+		{name: "stripe stale timestamp", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime.Add(6 * time.Minute),
+			headers: map[string]string{"Stripe-Signature": "t=" + stripeTimestamp + ",v1=" + stripeValid}, wantErr: true},
+		{name: "stripe future timestamp", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime.Add(-6 * time.Minute),
+			headers: map[string]string{"Stripe-Signature": "t=" + stripeTimestamp + ",v1=" + stripeValid}, wantErr: true},
+		{name: "stripe missing timestamp", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime,
+			headers: map[string]string{"Stripe-Signature": "v1=" + stripeValid}, wantErr: true},
+		{name: "stripe missing v1", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime,
+			headers: map[string]string{"Stripe-Signature": "t=" + stripeTimestamp}, wantErr: true},
+		{name: "stripe non-numeric timestamp", scheme: SignatureSchemeStripe, secret: "whsec_test", body: stripeBody, now: stripeTime,
+			headers: map[string]string{"Stripe-Signature": "t=yesterday,v1=" + stripeValid}, wantErr: true},
+
+		// Slack
+		{name: "slack valid", scheme: SignatureSchemeSlack, secret: slackSecret, body: slackBody, now: slackTime,
+			headers: map[string]string{"X-Slack-Signature": slackSignature, "X-Slack-Request-Timestamp": slackTimestamp}},
+		{name: "slack wrong secret", scheme: SignatureSchemeSlack, secret: "not-the-secret", body: slackBody, now: slackTime,
+			headers: map[string]string{"X-Slack-Signature": slackSignature, "X-Slack-Request-Timestamp": slackTimestamp}, wantErr: true, mismatch: true},
+		{name: "slack stale timestamp", scheme: SignatureSchemeSlack, secret: slackSecret, body: slackBody, now: slackTime.Add(10 * time.Minute),
+			headers: map[string]string{"X-Slack-Signature": slackSignature, "X-Slack-Request-Timestamp": slackTimestamp}, wantErr: true},
+		{name: "slack missing version prefix", scheme: SignatureSchemeSlack, secret: slackSecret, body: slackBody, now: slackTime,
+			headers: map[string]string{"X-Slack-Signature": slackSignature[3:], "X-Slack-Request-Timestamp": slackTimestamp}, wantErr: true},
+		{name: "slack missing timestamp", scheme: SignatureSchemeSlack, secret: slackSecret, body: slackBody, now: slackTime,
+			headers: map[string]string{"X-Slack-Signature": slackSignature}, wantErr: true},
+
+		// Configuration
+		{name: "no scheme accepts anything", scheme: SignatureSchemeNone, body: "anything"},
+		{name: "missing secret", scheme: SignatureSchemeGitHub, body: githubBody,
+			headers: map[string]string{"X-Hub-Signature-256": githubSignature}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := &database.Webhook{
+				VerificationScheme: tt.scheme,
+				Secret:             tt.secret,
+				SignatureHeader:    tt.header,
+			}
+			headers := http.Header{}
+			for name, value := range tt.headers {
+				headers.Set(name, value)
+			}
+
+			err := VerifyWebhookSignature(webhook, headers.Get, []byte(tt.body), tt.now)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("signature was accepted, want an error")
+			}
+			if tt.mismatch && !errors.Is(err, ErrSignatureMismatch) {
+				t.Errorf("error = %v, want ErrSignatureMismatch", err)
+			}
+		})
+	}
+}
+
+func TestSignatureToleranceOverride(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Add(-2*time.Minute).Unix(), 10)
+	body := `{"ok":true}`
+
+	webhook := &database.Webhook{
+		VerificationScheme:        SignatureSchemeStripe,
+		Secret:                    "whsec_test",
+		SignatureToleranceSeconds: 60,
+	}
+	headers := http.Header{}
+	headers.Set("Stripe-Signature", "t="+timestamp+",v1="+stripeSignature("whsec_test", timestamp, body))
+
+	if err := VerifyWebhookSignature(webhook, headers.Get, []byte(body), now); err == nil {
+		t.Error("a signature two minutes old passed a 60 second tolerance")
+	}
+
+	webhook.SignatureToleranceSeconds = 180
+	if err := VerifyWebhookSignature(webhook, headers.Get, []byte(body), now); err != nil {
+		t.Errorf("a signature two minutes old failed a 180 second tolerance: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"vigil/internal/database"
+)
+
+// invalidSignatureWindow is the window over which invalid signatures are counted
+const invalidSignatureWindow = 10 * time.Minute
+
+// RecordInvalidWebhookSignature counts an invalid signature for a webhook and raises an
+// alert when the count within the window reaches the webhook's threshold
+func (s *Service) RecordInvalidWebhookSignature(webhook *database.Webhook) {
+	if webhook.InvalidSignatureAlertThreshold <= 0 {
+		return
+	}
+
+	ctx := context.Background()
+	key := invalidSignatureCountKey(webhook.ID)
+
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		s.log.Errorf("Failed to count invalid signature for webhook %d: %v", webhook.ID, err)
+		return
+	}
+
+	// Start the window on the first invalid signature
+	if count == 1 {
+		s.redis.Expire(ctx, key, invalidSignatureWindow)
+	}
+
+	if count >= int64(webhook.InvalidSignatureAlertThreshold) {
+		s.createWebhookAlert(webhook, "webhook_invalid_signature",
+			fmt.Sprintf("Webhook %s received %d deliveries with invalid signatures in the last %v", webhook.Name, count, invalidSignatureWindow),
+			"high")
+		s.redis.Set(ctx, invalidSignatureAlertKey(webhook.ID), 1, 0)
+	}
+}
+
+// RecordValidWebhookSignature resolves an invalid signature alert once the burst is over:
+// the window has expired or its count is back below the threshold. A valid delivery
+// mixed into an ongoing burst leaves the alert open, so it is not raised again on the
+// next invalid request. Only Redis is consulted unless an alert was raised.
+func (s *Service) RecordValidWebhookSignature(webhook *database.Webhook) {
+	ctx := context.Background()
+
+	alerted, err := s.redis.Exists(ctx, invalidSignatureAlertKey(webhook.ID)).Result()
+	if err != nil || alerted == 0 {
+		return
+	}
+
+	count, err := s.redis.Get(ctx, invalidSignatureCountKey(webhook.ID)).Int64()
+	if err != nil && err != redis.Nil {
+		return
+	}
+	if webhook.InvalidSignatureAlertThreshold > 0 && count >= int64(webhook.InvalidSignatureAlertThreshold) {
+		return
+	}
+
+	s.resolveWebhookAlerts(webhook.ID, "webhook_invalid_signature")
+	s.redis.Del(ctx, invalidSignatureAlertKey(webhook.ID))
+}
+
+// invalidSignatureCountKey counts a webhook's invalid signatures in the current window
+func invalidSignatureCountKey(webhookID uint) string {
+	return fmt.Sprintf("webhook:%d:invalid_signatures", webhookID)
+}
+
+// invalidSignatureAlertKey is set while a webhook may have an open invalid signature alert
+func invalidSignatureAlertKey(webhookID uint) string {
+	return fmt.Sprintf("webhook:%d:invalid_signature_alert", webhookID)
+}
@@ -89,21 +89,76 @@ func (s *Server) setupRoutes() {
 	orgs.Put("/:id", handlers.UpdateOrganization(s.db))
 	orgs.Delete("/:id", handlers.DeleteOrganization(s.db))
 
+	// Organization secrets (write-only values)
+	secrets := protected.Group("/secrets")
+	secrets.Get("/", handlers.GetSecrets(s.db))
+	secrets.Post("/", handlers.CreateSecret(s.db))
+	secrets.Put("/:id", handlers.UpdateSecret(s.db))
+	secrets.Delete("/:id", handlers.DeleteSecret(s.db))
+
 	// Monitors
 	monitors := protected.Group("/monitors")
 	monitors.Get("/", handlers.GetMonitors(s.db))
 	monitors.Post("/", handlers.CreateMonitor(s.db, s.monitorService))
+	monitors.Get("/dependencies", handlers.GetDependencyTree(s.db, s.monitorService))
 	monitors.Get("/:id", handlers.GetMonitor(s.db))
 	monitors.Put("/:id", handlers.UpdateMonitor(s.db, s.monitorService))
 	monitors.Delete("/:id", handlers.DeleteMonitor(s.db, s.monitorService))
 	monitors.Get("/:id/checks", handlers.GetMonitorChecks(s.db))
 	monitors.Get("/:id/status", handlers.GetMonitorStatus(s.monitorService))
+	monitors.Get("/:id/parents", handlers.GetMonitorParents(s.db))
+	monitors.Put("/:id/parents", handlers.SetMonitorParents(s.db))
 
 	// Alerts
 	alerts := protected.Group("/alerts")
 	alerts.Get("/", handlers.GetAlerts(s.db))
 	alerts.Get("/:id", handlers.GetAlert(s.db))
 	alerts.Put("/:id/resolve", handlers.ResolveAlert(s.db))
+	alerts.Post("/:id/ack", handlers.AcknowledgeAlert(s.db))
+	alerts.Post("/:id/assign", handlers.AssignAlert(s.db))
+	alerts.Post("/:id/notes", handlers.AddAlertNote(s.db))
+	alerts.Get("/:id/timeline", handlers.GetAlertTimeline(s.db))
+
+	// Incidents
+	incidents := protected.Group("/incidents")
+	incidents.Get("/", handlers.GetIncidents(s.db))
+	incidents.Get("/:id", handlers.GetIncident(s.db))
+	incidents.Put("/:id", handlers.UpdateIncident(s.db))
+	incidents.Post("/:id/updates", handlers.AddIncidentUpdate(s.db))
+
+	incidentRules := protected.Group("/incident-rules")
+	incidentRules.Get("/", handlers.GetIncidentRules(s.db))
+	incidentRules.Post("/", handlers.CreateIncidentRule(s.db))
+	incidentRules.Put("/:id", handlers.UpdateIncidentRule(s.db))
+	incidentRules.Delete("/:id", handlers.DeleteIncidentRule(s.db))
+
+	// Postmortems
+	postmortems := protected.Group("/postmortems")
+	postmortems.Get("/", handlers.GetPostmortems(s.db))
+	postmortems.Post("/", handlers.CreatePostmortem(s.db))
+	postmortems.Get("/:id", handlers.GetPostmortem(s.db, s.monitorService))
+	postmortems.Put("/:id", handlers.UpdatePostmortem(s.db))
+	postmortems.Delete("/:id", handlers.DeletePostmortem(s.db))
+	postmortems.Get("/:id/export", handlers.ExportPostmortem(s.db, s.monitorService))
+	postmortems.Post("/:id/action-items", handlers.CreatePostmortemActionItem(s.db))
+	postmortems.Put("/:id/action-items/:itemId", handlers.UpdatePostmortemActionItem(s.db))
+	postmortems.Delete("/:id/action-items/:itemId", handlers.DeletePostmortemActionItem(s.db))
+
+	// Alert rules
+	alertRules := protected.Group("/alert-rules")
+	alertRules.Get("/", handlers.GetAlertRules(s.db))
+	alertRules.Post("/", handlers.CreateAlertRule(s.db))
+	alertRules.Get("/:id", handlers.GetAlertRule(s.db))
+	alertRules.Put("/:id", handlers.UpdateAlertRule(s.db, s.monitorService))
+	alertRules.Delete("/:id", handlers.DeleteAlertRule(s.db, s.monitorService))
+
+	// Maintenance windows
+	maintenance := protected.Group("/maintenance-windows")
+	maintenance.Get("/", handlers.GetMaintenanceWindows(s.db))
+	maintenance.Post("/", handlers.CreateMaintenanceWindow(s.db))
+	maintenance.Get("/:id", handlers.GetMaintenanceWindow(s.db))
+	maintenance.Put("/:id", handlers.UpdateMaintenanceWindow(s.db))
+	maintenance.Delete("/:id", handlers.DeleteMaintenanceWindow(s.db))
 
 	// Notification channels
 	channels := protected.Group("/notification-channels")
@@ -113,6 +168,33 @@ func (s *Server) setupRoutes() {
 	channels.Put("/:id", handlers.UpdateNotificationChannel(s.db))
 	channels.Delete("/:id", handlers.DeleteNotificationChannel(s.db))
 
+	// Escalation policies
+	escalationPolicies := protected.Group("/escalation-policies")
+	escalationPolicies.Get("/", handlers.GetEscalationPolicies(s.db))
+	escalationPolicies.Post("/", handlers.CreateEscalationPolicy(s.db))
+	escalationPolicies.Get("/:id", handlers.GetEscalationPolicy(s.db))
+	escalationPolicies.Put("/:id", handlers.UpdateEscalationPolicy(s.db))
+	escalationPolicies.Delete("/:id", handlers.DeleteEscalationPolicy(s.db))
+
+	// On-call schedules
+	onCall := protected.Group("/on-call-schedules")
+	onCall.Get("/", handlers.GetOnCallSchedules(s.db))
+	onCall.Post("/", handlers.CreateOnCallSchedule(s.db))
+	onCall.Get("/on-call", handlers.GetOnCallNow(s.db))
+	onCall.Get("/:id", handlers.GetOnCallSchedule(s.db))
+	onCall.Put("/:id", handlers.UpdateOnCallSchedule(s.db))
+	onCall.Delete("/:id", handlers.DeleteOnCallSchedule(s.db))
+	onCall.Get("/:id/on-call", handlers.GetOnCall(s.db))
+	onCall.Post("/:id/overrides", handlers.CreateOnCallOverride(s.db))
+	onCall.Delete("/:id/overrides/:overrideId", handlers.DeleteOnCallOverride(s.db))
+
+	// Contact methods of the current user
+	contactMethods := protected.Group("/contact-methods")
+	contactMethods.Get("/", handlers.GetContactMethods(s.db))
+	contactMethods.Post("/", handlers.CreateContactMethod(s.db))
+	contactMethods.Put("/:id", handlers.UpdateContactMethod(s.db))
+	contactMethods.Delete("/:id", handlers.DeleteContactMethod(s.db))
+
 	// Webhooks
 	webhooks := protected.Group("/webhooks")
 	webhooks.Get("/", handlers.GetWebhooks(s.db))
@@ -120,10 +202,22 @@ func (s *Server) setupRoutes() {
 	webhooks.Get("/:id", handlers.GetWebhook(s.db))
 	webhooks.Put("/:id", handlers.UpdateWebhook(s.db))
 	webhooks.Delete("/:id", handlers.DeleteWebhook(s.db))
+	webhooks.Get("/:id/analytics", handlers.GetWebhookAnalytics(s.db))
 	webhooks.Get("/:id/deliveries", handlers.GetWebhookDeliveries(s.db))
+	webhooks.Get("/:id/deliveries/:deliveryId", handlers.GetWebhookDelivery(s.db))
+	webhooks.Post("/:id/deliveries/:deliveryId/replay", handlers.ReplayWebhookDelivery(s.db, s.monitorService))
+	webhooks.Post("/:id/replay", handlers.ReplayWebhookDeliveries(s.db, s.monitorService))
+	webhooks.Post("/:id/rotate-token", handlers.RotateWebhookToken(s.db, s.monitorService))
+	webhooks.Get("/:id/destinations", handlers.GetWebhookDestinations(s.db))
+	webhooks.Post("/:id/destinations", handlers.CreateWebhookDestination(s.db))
+	webhooks.Put("/:id/destinations/:destinationId", handlers.UpdateWebhookDestination(s.db))
+	webhooks.Delete("/:id/destinations/:destinationId", handlers.DeleteWebhookDestination(s.db))
+	webhooks.Get("/:id/dead-letters", handlers.GetWebhookDeadLetters(s.db))
+	webhooks.Delete("/:id/dead-letters", handlers.PurgeWebhookDeadLetters(s.db, s.monitorService))
+	webhooks.Post("/:id/dead-letters/redrive", handlers.RedriveWebhookDeadLetters(s.db, s.monitorService))
 
 	// Webhook receiver (public endpoint)
-	s.app.Post("/webhook/:id", handlers.ReceiveWebhook(s.db))
+	s.app.All("/webhook/:token", handlers.ReceiveWebhook(s.db, s.monitorService))
 
 	// Interest list routes (public)
 	interest := s.app.Group("/api/interest")